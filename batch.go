@@ -0,0 +1,141 @@
+package gobergamot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBatchMaxWords is a default limit of words in a batch of requests coalesced by Pool.
+const DefaultBatchMaxWords = 1024
+
+// collectBatch takes requests compatible with the first one from the queue
+// until batch window is elapsed or words limit is reached.
+func (p *Pool) collectBatch(first *workerRequest) []*workerRequest {
+	p.collectingWorkers.Add(1)
+	defer p.collectingWorkers.Add(-1)
+
+	batch := []*workerRequest{first}
+	words := countWords(first.reqs)
+	if words >= p.cfg.BatchMaxWords {
+		return batch
+	}
+
+	timer := time.NewTimer(p.cfg.BatchWindow)
+	defer timer.Stop()
+	for {
+		req, empty := p.queue.popIf(func(req *workerRequest) bool {
+			return req.detailed == first.detailed && words+countWords(req.reqs) <= p.cfg.BatchMaxWords
+		})
+		switch {
+		case req != nil:
+			batch = append(batch, req)
+			words += countWords(req.reqs)
+			continue
+		case !empty:
+			// the next request does not fit into the batch
+			return batch
+		}
+
+		select {
+		case <-p.done:
+			return batch
+		case <-timer.C:
+			return batch
+		case <-p.queue.ready:
+		}
+	}
+}
+
+// handleBatch translates requests of the batch with a single call and sends responses to the callers.
+// Returns error of the translation. If the batch of several requests fails with fatal error, responses
+// are not sent and the requests are returned to be retried one by one.
+func handleBatch(workerID uint, translator *Translator, batch []*workerRequest) ([]*workerRequest, error) {
+	active := batch[:0]
+	for _, req := range batch {
+		if err := req.ctx.Err(); err != nil {
+			// caller has already gone
+			req.respChan <- workerResponse{err: err}
+			continue
+		}
+		active = append(active, req)
+	}
+	switch len(active) {
+	case 0:
+		return nil, nil
+	case 1:
+		resp := handleWorkerRequest(translator, active[0])
+		resp.workerID, resp.batchSize, resp.takenAt = workerID, 1, active[0].takenAt
+		active[0].respChan <- resp
+		return nil, resp.err
+	}
+
+	ctx, cancel := batchContext(active)
+	defer cancel()
+	merged := &workerRequest{ctx: ctx, detailed: active[0].detailed}
+	for _, req := range active {
+		merged.reqs = append(merged.reqs, req.reqs...)
+	}
+
+	resp := handleWorkerRequest(translator, merged)
+	if resp.err == nil && len(resp.outputs)+len(resp.results) != len(merged.reqs) {
+		resp.err = fmt.Errorf("expected %d translation results, got %d", len(merged.reqs), len(resp.outputs)+len(resp.results))
+	}
+	if resp.err != nil && isFatalError(resp.err) {
+		// a single request may break the module, e.g. by running out of memory,
+		// so failing the whole batch would fail the other requests for nothing
+		return active, resp.err
+	}
+	offset := 0
+	for _, req := range active {
+		reqResp := workerResponse{
+			err:       resp.err,
+			workerID:  workerID,
+			batchSize: len(active),
+			takenAt:   req.takenAt,
+		}
+		if resp.err == nil {
+			end := offset + len(req.reqs)
+			if req.detailed {
+				reqResp.results = resp.results[offset:end:end]
+			} else {
+				reqResp.outputs = resp.outputs[offset:end:end]
+			}
+			offset = end
+		}
+		req.respChan <- reqResp
+	}
+	return nil, resp.err
+}
+
+// batchContext returns a context which is done when contexts of all the requests are done.
+// Values are taken from the first request context.
+func batchContext(batch []*workerRequest) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(batch[0].ctx))
+	var remaining atomic.Int64
+	remaining.Store(int64(len(batch)))
+	stops := make([]func() bool, len(batch))
+	for i := range batch {
+		stops[i] = context.AfterFunc(batch[i].ctx, func() {
+			if remaining.Add(-1) == 0 {
+				cancel()
+			}
+		})
+	}
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
+
+func countWords(requests []TranslationRequest) uint {
+	var words uint
+	for i := range requests {
+		words += uint(len(strings.Fields(requests[i].Text)))
+	}
+	return words
+}
@@ -0,0 +1,233 @@
+package gobergamot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	ErrBundleFileMissing    = errors.New("bundle file is missing")
+	ErrAmbiguousBundleFiles = errors.New("multiple files of the same kind in bundle directory")
+	ErrLanguagePairMismatch = errors.New("bundle files have different language pairs")
+)
+
+// BundleInfo describes a bundle loaded by LoadBundleFromDir.
+type BundleInfo struct {
+	// Language pair inferred from file names
+	LanguagePair LanguagePair
+
+	// Paths of the loaded files in the file system
+	ModelPath            string
+	LexicalShortlistPath string
+	// Either VocabularyPath or SourceVocabularyPath and TargetVocabularyPath are not empty
+	VocabularyPath       string
+	SourceVocabularyPath string
+	TargetVocabularyPath string
+	// Empty if the bundle has no quality estimation model
+	QualityModelPath string
+}
+
+type bundleFileKind int
+
+const (
+	bundleModel bundleFileKind = iota
+	bundleLexicalShortlist
+	bundleVocabulary
+	bundleSourceVocabulary
+	bundleTargetVocabulary
+	bundleQualityModel
+)
+
+func (k bundleFileKind) String() string {
+	switch k {
+	case bundleModel:
+		return "model"
+	case bundleLexicalShortlist:
+		return "lexical shortlist"
+	case bundleVocabulary:
+		return "vocabulary"
+	case bundleSourceVocabulary:
+		return "source vocabulary"
+	case bundleTargetVocabulary:
+		return "target vocabulary"
+	case bundleQualityModel:
+		return "quality model"
+	default:
+		return "unknown"
+	}
+}
+
+// bundleFilePatterns match file names used in https://github.com/mozilla/firefox-translations-models,
+// e.g. model.esen.intgemm.alphas.bin.gz, lex.50.50.esen.s2t.bin.gz or vocab.esen.spm.gz.
+// The first submatch is a language pair code.
+var bundleFilePatterns = [...]struct {
+	kind    bundleFileKind
+	pattern *regexp.Regexp
+}{
+	{kind: bundleModel, pattern: regexp.MustCompile(`^model\.([a-z]+)\.intgemm[^.]*(?:\.alphas)?\.bin(?:\.gz|\.zst)?$`)},
+	{kind: bundleLexicalShortlist, pattern: regexp.MustCompile(`^lex\.(?:[0-9]+\.)*([a-z]+)\.s2t\.bin(?:\.gz|\.zst)?$`)},
+	{kind: bundleVocabulary, pattern: regexp.MustCompile(`^vocab\.([a-z]+)\.spm(?:\.gz|\.zst)?$`)},
+	{kind: bundleSourceVocabulary, pattern: regexp.MustCompile(`^srcvocab\.([a-z]+)\.spm(?:\.gz|\.zst)?$`)},
+	{kind: bundleTargetVocabulary, pattern: regexp.MustCompile(`^trgvocab\.([a-z]+)\.spm(?:\.gz|\.zst)?$`)},
+	{kind: bundleQualityModel, pattern: regexp.MustCompile(`^qualityModel\.([a-z]+)\.bin(?:\.gz|\.zst)?$`)},
+}
+
+// LoadBundleFromDir loads FilesBundle from directory in the file system. Files must be named like in
+// https://github.com/mozilla/firefox-translations-models (e.g. model.esen.intgemm.alphas.bin,
+// lex.50.50.esen.s2t.bin and vocab.esen.spm) and can be compressed with gzip or zstd (with .gz or .zst extension).
+// Language pair of the bundle is inferred from file names.
+//
+// The files are read into memory as is, so the returned FilesBundle does not hold any opened files.
+// Compressed files are decompressed when the bundle is loaded into Translator.
+func LoadBundleFromDir(fsys fs.FS, dir string) (FilesBundle, BundleInfo, error) {
+	paths, info, err := findBundleFiles(fsys, dir)
+	if err != nil {
+		return FilesBundle{}, BundleInfo{}, err
+	}
+
+	var files FilesBundle
+	for _, file := range bundleReaders(&files) {
+		// optional files are left nil
+		if paths[file.kind] == "" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, paths[file.kind])
+		if err != nil {
+			return FilesBundle{}, BundleInfo{}, fmt.Errorf("failed to read %s: %w", file.kind, err)
+		}
+		*file.reader = bytes.NewBuffer(data)
+	}
+
+	return files, info, nil
+}
+
+// OpenBundleFromDir is similar to LoadBundleFromDir, but opens files of the directory in the local file system
+// instead of reading them into memory. Pool maps such files into memory, so their data is read lazily
+// from page cache and is not copied into Go heap. Translator reads the files directly into WASM memory.
+//
+// Paths in BundleInfo are relative to dir. Returned function closes the files. They can be closed once the bundle is loaded into Translator or Pool.
+func OpenBundleFromDir(dir string) (FilesBundle, BundleInfo, func() error, error) {
+	paths, info, err := findBundleFiles(os.DirFS(dir), ".")
+	if err != nil {
+		return FilesBundle{}, BundleInfo{}, nil, err
+	}
+
+	var (
+		files  FilesBundle
+		opened []*os.File
+	)
+	closeFiles := func() error {
+		var err error
+		for _, f := range opened {
+			err = errors.Join(err, f.Close())
+		}
+		return err
+	}
+	for _, file := range bundleReaders(&files) {
+		if paths[file.kind] == "" {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(paths[file.kind])))
+		if err != nil {
+			return FilesBundle{}, BundleInfo{}, nil, errors.Join(
+				fmt.Errorf("failed to open %s: %w", file.kind, err),
+				closeFiles(),
+			)
+		}
+		opened = append(opened, f)
+		*file.reader = f
+	}
+
+	return files, info, closeFiles, nil
+}
+
+type bundleReader struct {
+	kind   bundleFileKind
+	reader *io.Reader
+}
+
+// bundleReaders returns readers of the bundle by their kinds.
+func bundleReaders(files *FilesBundle) []bundleReader {
+	return []bundleReader{
+		{kind: bundleModel, reader: &files.Model},
+		{kind: bundleLexicalShortlist, reader: &files.LexicalShortlist},
+		{kind: bundleVocabulary, reader: &files.Vocabulary},
+		{kind: bundleSourceVocabulary, reader: &files.SourceVocabulary},
+		{kind: bundleTargetVocabulary, reader: &files.TargetVocabulary},
+		{kind: bundleQualityModel, reader: &files.QualityModel},
+	}
+}
+
+// findBundleFiles finds paths of bundle files in the directory by their names.
+func findBundleFiles(fsys fs.FS, dir string) ([len(bundleFilePatterns)]string, BundleInfo, error) {
+	var paths [len(bundleFilePatterns)]string
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return paths, BundleInfo{}, err
+	}
+
+	var pairCode string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, p := range bundleFilePatterns {
+			match := p.pattern.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+			if paths[p.kind] != "" {
+				return paths, BundleInfo{}, fmt.Errorf(
+					"%w: %s and %s",
+					ErrAmbiguousBundleFiles,
+					path.Base(paths[p.kind]),
+					entry.Name(),
+				)
+			}
+			if pairCode != "" && pairCode != match[1] {
+				return paths, BundleInfo{}, fmt.Errorf("%w: %s and %s", ErrLanguagePairMismatch, pairCode, match[1])
+			}
+			pairCode = match[1]
+			paths[p.kind] = path.Join(dir, entry.Name())
+			break
+		}
+	}
+
+	required := []bundleFileKind{bundleModel, bundleLexicalShortlist}
+	if paths[bundleSourceVocabulary] != "" || paths[bundleTargetVocabulary] != "" {
+		required = append(required, bundleSourceVocabulary, bundleTargetVocabulary)
+	} else {
+		required = append(required, bundleVocabulary)
+	}
+	for _, kind := range required {
+		if paths[kind] == "" {
+			return paths, BundleInfo{}, fmt.Errorf("%w: %s", ErrBundleFileMissing, kind)
+		}
+	}
+
+	info := BundleInfo{
+		LanguagePair:         parseLanguagePairCode(pairCode),
+		ModelPath:            paths[bundleModel],
+		LexicalShortlistPath: paths[bundleLexicalShortlist],
+		VocabularyPath:       paths[bundleVocabulary],
+		SourceVocabularyPath: paths[bundleSourceVocabulary],
+		TargetVocabularyPath: paths[bundleTargetVocabulary],
+		QualityModelPath:     paths[bundleQualityModel],
+	}
+
+	return paths, info, nil
+}
+
+// parseLanguagePairCode splits language pair code like "esen" into source and target language codes.
+func parseLanguagePairCode(code string) LanguagePair {
+	half := len(code) / 2
+	return LanguagePair{From: code[:half], To: code[half:]}
+}
@@ -0,0 +1,214 @@
+package gobergamot_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+func TestLoadBundleFromDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		fsys     fstest.MapFS
+		wantErr  error
+		wantInfo gobergamot.BundleInfo
+	}{
+		{
+			name: "raw files",
+			dir:  "esen",
+			fsys: fstest.MapFS{
+				"esen/model.esen.intgemm.alphas.bin": {Data: []byte("model")},
+				"esen/lex.50.50.esen.s2t.bin":        {Data: []byte("lex")},
+				"esen/vocab.esen.spm":                {Data: []byte("vocab")},
+				"esen/README.md":                     {Data: []byte("readme")},
+			},
+			wantInfo: gobergamot.BundleInfo{
+				LanguagePair:         gobergamot.LanguagePair{From: "es", To: "en"},
+				ModelPath:            "esen/model.esen.intgemm.alphas.bin",
+				LexicalShortlistPath: "esen/lex.50.50.esen.s2t.bin",
+				VocabularyPath:       "esen/vocab.esen.spm",
+			},
+		},
+		{
+			name: "compressed files with quality model",
+			dir:  "enes",
+			fsys: fstest.MapFS{
+				"enes/model.enes.intgemm.alphas.bin.gz": {Data: gzipped(t, "model")},
+				"enes/lex.50.50.enes.s2t.bin.gz":        {Data: gzipped(t, "lex")},
+				"enes/vocab.enes.spm.gz":                {Data: gzipped(t, "vocab")},
+				"enes/qualityModel.enes.bin.gz":         {Data: gzipped(t, "qe")},
+			},
+			wantInfo: gobergamot.BundleInfo{
+				LanguagePair:         gobergamot.LanguagePair{From: "en", To: "es"},
+				ModelPath:            "enes/model.enes.intgemm.alphas.bin.gz",
+				LexicalShortlistPath: "enes/lex.50.50.enes.s2t.bin.gz",
+				VocabularyPath:       "enes/vocab.enes.spm.gz",
+				QualityModelPath:     "enes/qualityModel.enes.bin.gz",
+			},
+		},
+		{
+			name: "separate vocabularies",
+			dir:  "enzh",
+			fsys: fstest.MapFS{
+				"enzh/model.enzh.intgemm.alphas.bin": {Data: []byte("model")},
+				"enzh/lex.50.50.enzh.s2t.bin":        {Data: []byte("lex")},
+				"enzh/srcvocab.enzh.spm":             {Data: []byte("srcvocab")},
+				"enzh/trgvocab.enzh.spm":             {Data: []byte("trgvocab")},
+			},
+			wantInfo: gobergamot.BundleInfo{
+				LanguagePair:         gobergamot.LanguagePair{From: "en", To: "zh"},
+				ModelPath:            "enzh/model.enzh.intgemm.alphas.bin",
+				LexicalShortlistPath: "enzh/lex.50.50.enzh.s2t.bin",
+				SourceVocabularyPath: "enzh/srcvocab.enzh.spm",
+				TargetVocabularyPath: "enzh/trgvocab.enzh.spm",
+			},
+		},
+		{
+			name: "missing target vocabulary",
+			dir:  "enzh",
+			fsys: fstest.MapFS{
+				"enzh/model.enzh.intgemm.alphas.bin": {Data: []byte("model")},
+				"enzh/lex.50.50.enzh.s2t.bin":        {Data: []byte("lex")},
+				"enzh/srcvocab.enzh.spm":             {Data: []byte("srcvocab")},
+			},
+			wantErr: gobergamot.ErrBundleFileMissing,
+		},
+		{
+			name: "missing shortlist",
+			dir:  "esen",
+			fsys: fstest.MapFS{
+				"esen/model.esen.intgemm.alphas.bin": {Data: []byte("model")},
+				"esen/vocab.esen.spm":                {Data: []byte("vocab")},
+			},
+			wantErr: gobergamot.ErrBundleFileMissing,
+		},
+		{
+			name: "ambiguous models",
+			dir:  "esen",
+			fsys: fstest.MapFS{
+				"esen/model.esen.intgemm.alphas.bin":    {Data: []byte("model")},
+				"esen/model.esen.intgemm.alphas.bin.gz": {Data: gzipped(t, "model")},
+			},
+			wantErr: gobergamot.ErrAmbiguousBundleFiles,
+		},
+		{
+			name: "mismatched language pairs",
+			dir:  "esen",
+			fsys: fstest.MapFS{
+				"esen/model.esen.intgemm.alphas.bin": {Data: []byte("model")},
+				"esen/lex.50.50.enes.s2t.bin":        {Data: []byte("lex")},
+			},
+			wantErr: gobergamot.ErrLanguagePairMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, info, err := gobergamot.LoadBundleFromDir(tt.fsys, tt.dir)
+			if tt.wantErr != nil || err != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("LoadBundleFromDir() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if info != tt.wantInfo {
+				t.Errorf("unexpected info\nexpected: %+v\ngot: %+v", tt.wantInfo, info)
+			}
+			readers := map[string]io.Reader{
+				info.ModelPath:            files.Model,
+				info.LexicalShortlistPath: files.LexicalShortlist,
+				info.VocabularyPath:       files.Vocabulary,
+				info.SourceVocabularyPath: files.SourceVocabulary,
+				info.TargetVocabularyPath: files.TargetVocabulary,
+				info.QualityModelPath:     files.QualityModel,
+			}
+			// skipping missing files
+			delete(readers, "")
+			for name, r := range readers {
+				if r == nil {
+					t.Errorf("file %s is missing", name)
+					continue
+				}
+				data, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("failed to read file %s: %v", name, err)
+				}
+				if !bytes.Equal(data, tt.fsys[name].Data) {
+					t.Errorf("file %s: expected %q, got %q", name, tt.fsys[name].Data, data)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenBundleFromDir(t *testing.T) {
+	dir := t.TempDir()
+	contents := map[string]string{
+		"model.esen.intgemm.alphas.bin": "model",
+		"lex.50.50.esen.s2t.bin":        "lex",
+		"vocab.esen.spm":                "vocab",
+	}
+	for name, data := range contents {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatalf("failed to write file %s: %v", name, err)
+		}
+	}
+
+	files, info, closeFiles, err := gobergamot.OpenBundleFromDir(dir)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer func() {
+		if err := closeFiles(); err != nil {
+			t.Errorf("failed to close files: %v", err)
+		}
+	}()
+
+	if info.LanguagePair != (gobergamot.LanguagePair{From: "es", To: "en"}) {
+		t.Errorf("unexpected language pair %v", info.LanguagePair)
+	}
+	if files.QualityModel != nil {
+		t.Errorf("expected no quality model")
+	}
+	for name, r := range map[string]io.Reader{
+		info.ModelPath:            files.Model,
+		info.LexicalShortlistPath: files.LexicalShortlist,
+		info.VocabularyPath:       files.Vocabulary,
+	} {
+		if _, ok := r.(*os.File); !ok {
+			t.Fatalf("file %s: expected *os.File, got %T", name, r)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("failed to read file %s: %v", name, err)
+		}
+		if string(data) != contents[name] {
+			t.Errorf("file %s: expected %q, got %q", name, contents[name], data)
+		}
+	}
+
+	if _, _, _, err := gobergamot.OpenBundleFromDir(t.TempDir()); !errors.Is(err, gobergamot.ErrBundleFileMissing) {
+		t.Errorf("expected ErrBundleFileMissing for empty directory, got %v", err)
+	}
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatalf("failed to compress data: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to compress data: %v", err)
+	}
+	return buf.Bytes()
+}
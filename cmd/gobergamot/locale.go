@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/i18n"
+	"github.com/KSpaceer/gobergamot/i18n/locale"
+)
+
+func runLocale(ctx context.Context, args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("locale", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gobergamot locale -model DIR [flags] FILE\n\n")
+		fmt.Fprintf(stderr, "Translates string values of the JSON, YAML or TOML locale file and writes the target locale file.\n\n")
+		flags.PrintDefaults()
+	}
+	var (
+		modelDir  = flags.String("model", "", "directory with model files named like in firefox-translations-models")
+		output    = flags.String("o", "", "output file, standard output is used by default")
+		existing  = flags.String("existing", "", "existing target locale file, only keys missing in it are translated")
+		format    = flags.String("format", "", "format of the files: json, yaml or toml, detected by extension by default")
+		batchSize = flags.Uint("batch-size", 32, "number of values translated in a single call")
+		workers   = flags.Uint("workers", 1, "number of translators working concurrently")
+		verbose   = flags.Bool("verbose", false, "write Bergamot logs to standard error")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *modelDir == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("-model and a single file are required")
+	}
+	if *batchSize == 0 || *workers == 0 {
+		return errors.New("-batch-size and -workers must be positive")
+	}
+
+	name := flags.Arg(0)
+	fileFormat, err := localeFormat(*format, name)
+	if err != nil {
+		return err
+	}
+	source, err := readLocaleFile(name, fileFormat)
+	if err != nil {
+		return err
+	}
+	var target *locale.File
+	if *existing != "" {
+		target, err = readLocaleFile(*existing, fileFormat)
+		if errors.Is(err, fs.ErrNotExist) {
+			// the first translation of the file
+			target, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	files, _, closeFiles, err := gobergamot.OpenBundleFromDir(*modelDir)
+	if err != nil {
+		return fmt.Errorf("failed to load model from %s: %w", *modelDir, err)
+	}
+	pool, err := newPool(ctx, files, *workers, *verbose, nil, stderr)
+	// the pool keeps the files mapped into memory, so they are not needed anymore
+	closeFiles()
+	if err != nil {
+		return err
+	}
+	defer pool.Close(context.Background())
+
+	opts := i18n.Options{BatchSize: int(*batchSize), Parallelism: int(*workers)}
+	if _, err := source.Translate(ctx, pool, opts, target); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if *output == "" {
+		_, err = source.WriteTo(stdout)
+		return err
+	}
+	var buf bytes.Buffer
+	if _, err := source.WriteTo(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(*output, buf.Bytes(), 0o644)
+}
+
+func localeFormat(format, name string) (locale.Format, error) {
+	switch format {
+	case "":
+		return locale.FormatOf(name)
+	case "json":
+		return locale.JSON, nil
+	case "yaml":
+		return locale.YAML, nil
+	case "toml":
+		return locale.TOML, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", locale.ErrUnknownFormat, format)
+	}
+}
+
+func readLocaleFile(name string, format locale.Format) (*locale.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	file, err := locale.Parse(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return file, nil
+}
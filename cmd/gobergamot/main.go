@@ -0,0 +1,68 @@
+// Command gobergamot translates texts with Bergamot models from the command line or over HTTP.
+//
+// Usage:
+//
+//	gobergamot <command> [flags] [arguments]
+//
+// Commands:
+//
+//	translate  translate lines of text from files or standard input
+//	locale     translate JSON, YAML or TOML locale file
+//	serve      serve LibreTranslate-compatible HTTP API
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{name: "translate", description: "translate lines of text from files or standard input", run: runTranslate},
+	{name: "locale", description: "translate JSON, YAML or TOML locale file", run: runLocale},
+	{name: "serve", description: "serve LibreTranslate-compatible HTTP API", run: runServe},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "gobergamot: %v\n", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		usage(stderr)
+		return flag.ErrHelp
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(ctx, args[1:], stdin, stdout, stderr)
+		}
+	}
+	usage(stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: gobergamot <command> [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(w, "\nRun 'gobergamot <command> -h' for command flags.\n")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/observer"
+	"github.com/KSpaceer/gobergamot/server"
+)
+
+// shutdownTimeout limits time of waiting for active requests to complete when the server stops
+const shutdownTimeout = 30 * time.Second
+
+func runServe(ctx context.Context, args []string, _ io.Reader, _, stderr io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gobergamot serve -models DIR [flags]\n\n")
+		fmt.Fprintf(stderr, "Serves LibreTranslate-compatible HTTP API using models from subdirectories of DIR.\n\n")
+		flags.PrintDefaults()
+	}
+	var (
+		addr      = flags.String("addr", ":5000", "address to listen on")
+		modelsDir = flags.String("models", "", "directory with subdirectories containing model files named like in firefox-translations-models")
+		workers   = flags.Uint("workers", 1, "number of translators per language pair")
+		verbose   = flags.Bool("verbose", false, "write Bergamot logs to standard error")
+		metrics   = flags.Bool("metrics", false, "serve Prometheus metrics at /metrics")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *modelsDir == "" {
+		flags.Usage()
+		return errors.New("-models is required")
+	}
+	if *workers == 0 {
+		return errors.New("-workers must be positive")
+	}
+
+	var (
+		obs gobergamot.Observer
+		mux = http.NewServeMux()
+	)
+	if *metrics {
+		prometheus := observer.NewPrometheus("")
+		obs = prometheus
+		mux.Handle("GET /metrics", prometheus)
+	}
+
+	pools, err := newPools(ctx, *modelsDir, *workers, *verbose, obs, stderr)
+	defer func() {
+		for _, pool := range pools {
+			pool.Close(context.Background())
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	translators := make(map[gobergamot.LanguagePair]server.Translator, len(pools))
+	for pair, pool := range pools {
+		translators[pair] = pool
+	}
+	handler, err := server.New(server.Config{Translators: translators})
+	if err != nil {
+		return err
+	}
+
+	mux.Handle("/", handler)
+	srv := &http.Server{Addr: *addr, Handler: mux}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(stderr, "listening on %s\n", *addr)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// newPools creates a pool of translators for every model directory inside modelsDir.
+// Directories without model files are skipped.
+func newPools(
+	ctx context.Context,
+	modelsDir string,
+	workers uint,
+	verbose bool,
+	obs gobergamot.Observer,
+	stderr io.Writer,
+) (map[gobergamot.LanguagePair]*gobergamot.Pool, error) {
+	entries, err := os.ReadDir(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make(map[gobergamot.LanguagePair]*gobergamot.Pool)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(modelsDir, entry.Name())
+		files, info, closeFiles, err := gobergamot.OpenBundleFromDir(dir)
+		if errors.Is(err, gobergamot.ErrBundleFileMissing) {
+			fmt.Fprintf(stderr, "skipping %s: %v\n", dir, err)
+			continue
+		}
+		if err != nil {
+			return pools, fmt.Errorf("failed to load model from %s: %w", dir, err)
+		}
+		if _, ok := pools[info.LanguagePair]; ok {
+			closeFiles()
+			return pools, fmt.Errorf("%s: duplicate model for %s", dir, info.LanguagePair)
+		}
+
+		pool, err := newPool(ctx, files, workers, verbose, obs, stderr)
+		// the pool keeps the files mapped into memory, so they are not needed anymore
+		closeFiles()
+		if err != nil {
+			return pools, fmt.Errorf("%s: %w", dir, err)
+		}
+		pools[info.LanguagePair] = pool
+		fmt.Fprintf(stderr, "loaded %s from %s\n", info.LanguagePair, dir)
+	}
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("no models found in %s", modelsDir)
+	}
+	return pools, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+// maxLineSize limits size of a single line of text to translate
+const maxLineSize = 16 * 1024 * 1024
+
+func runTranslate(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("translate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gobergamot translate -model DIR [flags] [FILE...]\n\n")
+		fmt.Fprintf(stderr, "Translates every line of the files (or standard input) and writes translations to standard output.\n\n")
+		flags.PrintDefaults()
+	}
+	var (
+		modelDir  = flags.String("model", "", "directory with model files named like in firefox-translations-models")
+		html      = flags.Bool("html", false, "treat input lines as HTML")
+		batchSize = flags.Uint("batch-size", 32, "number of lines translated in a single call")
+		workers   = flags.Uint("workers", 1, "number of translators working concurrently")
+		verbose   = flags.Bool("verbose", false, "write Bergamot logs to standard error")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *modelDir == "" {
+		flags.Usage()
+		return errors.New("-model is required")
+	}
+	if *batchSize == 0 || *workers == 0 {
+		return errors.New("-batch-size and -workers must be positive")
+	}
+
+	files, _, closeFiles, err := gobergamot.OpenBundleFromDir(*modelDir)
+	if err != nil {
+		return fmt.Errorf("failed to load model from %s: %w", *modelDir, err)
+	}
+	pool, err := newPool(ctx, files, *workers, *verbose, nil, stderr)
+	// the pool keeps the files mapped into memory, so they are not needed anymore
+	closeFiles()
+	if err != nil {
+		return err
+	}
+	defer pool.Close(context.Background())
+
+	opts := linesTranslationOptions{
+		batchSize:   int(*batchSize),
+		parallelism: int(*workers),
+		options:     gobergamot.TranslationOptions{HTML: *html},
+	}
+
+	if flags.NArg() == 0 {
+		return translateLines(ctx, pool, stdin, stdout, opts)
+	}
+	for _, name := range flags.Args() {
+		if err := translateFile(ctx, pool, name, stdout, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPool creates a pool of translators using the model files.
+func newPool(
+	ctx context.Context,
+	files gobergamot.FilesBundle,
+	workers uint,
+	verbose bool,
+	observer gobergamot.Observer,
+	stderr io.Writer,
+) (*gobergamot.Pool, error) {
+	cfg := gobergamot.PoolConfig{
+		Config:   gobergamot.Config{FilesBundle: files, Observer: observer},
+		PoolSize: workers,
+	}
+	// standard output is used for translations, so Bergamot logs must not be written there
+	cfg.Stdout, cfg.Stderr = io.Discard, io.Discard
+	if verbose {
+		cfg.Logger = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	pool, err := gobergamot.NewPool(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create translators: %w", err)
+	}
+	return pool, nil
+}
+
+func translateFile(
+	ctx context.Context,
+	tr batchTranslator,
+	name string,
+	output io.Writer,
+	opts linesTranslationOptions,
+) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := translateLines(ctx, tr, f, output, opts); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+type batchTranslator interface {
+	TranslateMultiple(ctx context.Context, requests ...gobergamot.TranslationRequest) ([]string, error)
+}
+
+type linesTranslationOptions struct {
+	// number of lines in a single TranslateMultiple call
+	batchSize int
+	// maximum number of concurrent TranslateMultiple calls
+	parallelism int
+	options     gobergamot.TranslationOptions
+}
+
+type linesBatch struct {
+	lines  []string
+	result chan linesBatchResult
+}
+
+type linesBatchResult struct {
+	outputs []string
+	err     error
+}
+
+// translateLines reads lines from input, translates them in batches concurrently
+// and writes translations to output in the order of input lines.
+func translateLines(
+	ctx context.Context,
+	tr batchTranslator,
+	input io.Reader,
+	output io.Writer,
+	opts linesTranslationOptions,
+) error {
+	// reading is stopped by cancellation of the parent context or by write failures
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// pending batches are given to the writer in the order of reading
+	pending := make(chan *linesBatch, opts.parallelism)
+	sem := make(chan struct{}, opts.parallelism)
+	writerErr := make(chan error, 1)
+
+	go func() {
+		var err error
+		bufferedOutput := bufio.NewWriter(output)
+		for batch := range pending {
+			result := <-batch.result
+			if err != nil {
+				// draining batches after failure
+				continue
+			}
+			err = result.err
+			for i := 0; err == nil && i < len(result.outputs); i++ {
+				_, err = fmt.Fprintln(bufferedOutput, result.outputs[i])
+			}
+			if err == nil {
+				err = bufferedOutput.Flush()
+			}
+			if err != nil {
+				cancel()
+			}
+		}
+		writerErr <- err
+	}()
+
+	dispatch := func(lines []string) {
+		batch := &linesBatch{lines: lines, result: make(chan linesBatchResult, 1)}
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			outputs, err := translateBatch(ctx, tr, batch.lines, opts.options)
+			batch.result <- linesBatchResult{outputs: outputs, err: err}
+		}()
+		pending <- batch
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(nil, maxLineSize)
+	lines := make([]string, 0, opts.batchSize)
+	for ctx.Err() == nil && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) == opts.batchSize {
+			dispatch(lines)
+			lines = make([]string, 0, opts.batchSize)
+		}
+	}
+	if len(lines) > 0 && ctx.Err() == nil {
+		dispatch(lines)
+	}
+	close(pending)
+
+	err := errors.Join(<-writerErr, scanner.Err())
+	if ctxErr := parent.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = errors.Join(err, ctxErr)
+	}
+	return err
+}
+
+// translateBatch translates non-empty lines, leaving empty ones as is.
+func translateBatch(
+	ctx context.Context,
+	tr batchTranslator,
+	lines []string,
+	options gobergamot.TranslationOptions,
+) ([]string, error) {
+	outputs := make([]string, len(lines))
+	requests := make([]gobergamot.TranslationRequest, 0, len(lines))
+	indices := make([]int, 0, len(lines))
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		requests = append(requests, gobergamot.TranslationRequest{Text: line, Options: options})
+		indices = append(indices, i)
+	}
+	if len(requests) == 0 {
+		return outputs, nil
+	}
+
+	translated, err := tr.TranslateMultiple(ctx, requests...)
+	if err != nil {
+		return nil, err
+	}
+	if len(translated) != len(requests) {
+		return nil, fmt.Errorf("expected %d translations, got %d", len(requests), len(translated))
+	}
+	for i, idx := range indices {
+		outputs[idx] = translated[i]
+	}
+	return outputs, nil
+}
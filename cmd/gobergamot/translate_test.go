@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+// upperTranslator "translates" texts into upper case.
+type upperTranslator struct {
+	mu      sync.Mutex
+	batches []int
+	failOn  string
+}
+
+func (t *upperTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	t.mu.Lock()
+	t.batches = append(t.batches, len(requests))
+	t.mu.Unlock()
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		if t.failOn != "" && requests[i].Text == t.failOn {
+			return nil, errors.New("translation failed")
+		}
+		outputs[i] = strings.ToUpper(requests[i].Text)
+	}
+	return outputs, nil
+}
+
+func TestTranslateLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		opts       linesTranslationOptions
+		failOn     string
+		wantOutput string
+		wantErr    bool
+	}{
+		{
+			name:       "single batch",
+			input:      "hello\nworld\n",
+			opts:       linesTranslationOptions{batchSize: 10, parallelism: 1},
+			wantOutput: "HELLO\nWORLD\n",
+		},
+		{
+			name:       "many batches in parallel",
+			input:      "a\nb\nc\nd\ne\nf\ng",
+			opts:       linesTranslationOptions{batchSize: 2, parallelism: 3},
+			wantOutput: "A\nB\nC\nD\nE\nF\nG\n",
+		},
+		{
+			name:       "empty lines",
+			input:      "a\n\nb\n\n",
+			opts:       linesTranslationOptions{batchSize: 2, parallelism: 2},
+			wantOutput: "A\n\nB\n\n",
+		},
+		{
+			name:    "translation error",
+			input:   "a\nb\nc\nd\n",
+			opts:    linesTranslationOptions{batchSize: 1, parallelism: 2},
+			failOn:  "c",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &upperTranslator{failOn: tt.failOn}
+			output := new(bytes.Buffer)
+			err := translateLines(context.Background(), tr, strings.NewReader(tt.input), output, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("translateLines() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if output.String() != tt.wantOutput {
+				t.Errorf("\nexpected: %q\ngot: %q", tt.wantOutput, output.String())
+			}
+			for _, size := range tr.batches {
+				if size > tt.opts.batchSize {
+					t.Errorf("batch size %d exceeds limit %d", size, tt.opts.batchSize)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateLines_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	output := new(bytes.Buffer)
+	opts := linesTranslationOptions{batchSize: 1, parallelism: 1}
+	err := translateLines(ctx, &upperTranslator{}, strings.NewReader("a\nb\n"), output, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	if err := run(context.Background(), []string{"unknown"}, nil, stdout, stderr); err == nil {
+		t.Errorf("expected error for unknown command")
+	}
+	if err := run(context.Background(), []string{"translate"}, nil, stdout, stderr); err == nil {
+		t.Errorf("expected error for missing model directory")
+	}
+}
@@ -0,0 +1,453 @@
+package gobergamot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KSpaceer/gobergamot/internal/errgroup"
+)
+
+// DocumentChunkBytes is the size limit of chunks which TranslateDocument splits texts into.
+// If Config.MaxInputBytes is smaller, it is used instead.
+const DocumentChunkBytes = 4096
+
+var ErrInputTooLarge = errors.New("input text is too large")
+
+// checkInputSize checks that texts of the requests don't exceed maxBytes (if it is positive).
+func checkInputSize(requests []TranslationRequest, maxBytes int) error {
+	if maxBytes <= 0 {
+		return nil
+	}
+	for i := range requests {
+		if len(requests[i].Text) > maxBytes {
+			return fmt.Errorf("%w: text %d has %d bytes, limit is %d", ErrInputTooLarge, i, len(requests[i].Text), maxBytes)
+		}
+	}
+	return nil
+}
+
+// TranslateDocument translates a text of any size. The text is split into chunks on paragraph
+// (blank lines) and, if paragraphs are too long, sentence boundaries. Chunks are translated in batches
+// and joined back with the original whitespace between them.
+//
+// HTML texts are split on blank lines, so their paragraphs must be complete HTML fragments. Too long paragraphs
+// are split only outside of elements: on sentence boundaries and whitespace after elements. So a paragraph
+// which is a single long element (e.g. <div> wrapping the whole document) is translated as a whole
+// and fails with ErrInputTooLarge if it exceeds Config.MaxInputBytes.
+//
+// If Placeholders option is used, texts are never split inside placeholders (e.g. ICU plural arguments).
+func (t *Translator) TranslateDocument(ctx context.Context, request TranslationRequest) (string, error) {
+	return translateDocument(ctx, request, t.cfg.MaxInputBytes, func(ctx context.Context, batches [][]TranslationRequest) ([][]string, error) {
+		outputs := make([][]string, len(batches))
+		for i := range batches {
+			var err error
+			outputs[i], err = t.TranslateMultiple(ctx, batches[i]...)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return outputs, nil
+	})
+}
+
+// TranslateDocument is similar to Translator.TranslateDocument except batches of chunks are translated
+// by the pool workers in parallel. At most PoolConfig.MaxSize batches are sent to the pool at once,
+// so a long document doesn't overflow the queue limited by PoolConfig.MaxQueueDepth.
+func (p *Pool) TranslateDocument(ctx context.Context, request TranslationRequest) (string, error) {
+	return translateDocument(ctx, request, p.cfg.MaxInputBytes, func(ctx context.Context, batches [][]TranslationRequest) ([][]string, error) {
+		// stopping translation of other batches if one fails
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		outputs := make([][]string, len(batches))
+		eg := errgroup.New()
+		sem := make(chan struct{}, max(p.cfg.MaxSize, 1))
+	loop:
+		for i := range batches {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break loop
+			}
+			i := i
+			eg.Go(func() error {
+				defer func() { <-sem }()
+				var err error
+				outputs[i], err = p.TranslateMultiple(ctx, batches[i]...)
+				if err != nil {
+					cancel()
+				}
+				return err
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			// the caller has gone before all batches were sent
+			return nil, err
+		}
+		return outputs, nil
+	})
+}
+
+// translateDocument splits the document into chunks, translates batches of them with translateBatches
+// and joins translated chunks.
+func translateDocument(
+	ctx context.Context,
+	request TranslationRequest,
+	maxInputBytes int,
+	translateBatches func(ctx context.Context, batches [][]TranslationRequest) ([][]string, error),
+) (string, error) {
+	chunkBytes := DocumentChunkBytes
+	if maxInputBytes > 0 && maxInputBytes < chunkBytes {
+		chunkBytes = maxInputBytes
+	}
+	parts := splitDocument(request.Text, chunkBytes, request.Options)
+
+	// grouping consecutive chunks into batches of chunkBytes size
+	var (
+		batches    [][]TranslationRequest
+		batchBytes int
+		chunks     int
+	)
+	for _, part := range parts {
+		if !part.translate {
+			continue
+		}
+		chunks++
+		if len(batches) == 0 || batchBytes+len(part.text) > chunkBytes {
+			batches = append(batches, nil)
+			batchBytes = 0
+		}
+		chunkRequest := request
+		chunkRequest.Text = part.text
+		batches[len(batches)-1] = append(batches[len(batches)-1], chunkRequest)
+		batchBytes += len(part.text)
+	}
+	if len(batches) == 0 {
+		// nothing to translate besides whitespace
+		return request.Text, nil
+	}
+
+	outputs, err := translateBatches(ctx, batches)
+	if err != nil {
+		return "", err
+	}
+	translated := slices.Concat(outputs...)
+	if len(translated) != chunks {
+		return "", fmt.Errorf("expected %d translated chunks but got %d", chunks, len(translated))
+	}
+
+	var sb strings.Builder
+	for _, part := range parts {
+		if !part.translate {
+			sb.WriteString(part.text)
+			continue
+		}
+		// the whitespace around chunks is restored from the original text
+		sb.WriteString(strings.TrimSpace(translated[0]))
+		translated = translated[1:]
+	}
+	return sb.String(), nil
+}
+
+// documentPart is either a chunk of a document to translate or whitespace between chunks.
+type documentPart struct {
+	text      string
+	translate bool
+}
+
+var (
+	// paragraphSeparator matches blank lines between paragraphs
+	paragraphSeparator = regexp.MustCompile(`\n[^\S\n]*\n\s*`)
+	// sentenceEnd matches punctuation ending a sentence along with the following whitespace
+	sentenceEnd = regexp.MustCompile(`[.!?…]+['"”’»)\]]*\s+|[。！？]+\s*`)
+	// wordEnd matches whitespace between words
+	wordEnd = regexp.MustCompile(`\s+`)
+)
+
+// splitLevel defines boundaries which a text is split on if it doesn't fit into a chunk.
+type splitLevel int
+
+const (
+	splitSentences splitLevel = iota
+	splitWords
+	splitRunes
+)
+
+// documentSplitter splits a document into parts.
+type documentSplitter struct {
+	maxBytes int
+	// placeholders defines if the text must not be cut inside placeholders
+	placeholders bool
+	parts        []documentPart
+}
+
+// splitDocument splits a document into chunks of maxBytes size at most and whitespace between them.
+// HTML paragraphs are split only outside of elements and texts with placeholders are split only outside
+// of placeholders, so their chunks may exceed maxBytes.
+func splitDocument(text string, maxBytes int, opts TranslationOptions) []documentPart {
+	s := documentSplitter{maxBytes: maxBytes, placeholders: opts.Placeholders}
+	separators := paragraphSeparator.FindAllStringIndex(text, -1)
+	if s.placeholders {
+		// blank lines may be inside ICU arguments
+		spans := placeholderSpans(text)
+		separators = slices.DeleteFunc(separators, func(loc []int) bool {
+			return insideSpan(spans, loc[0]) || insideSpan(spans, loc[1])
+		})
+	}
+	prev := 0
+	for _, loc := range separators {
+		s.addParagraph(text[prev:loc[0]], opts.HTML)
+		s.addSpace(text[loc[0]:loc[1]])
+		prev = loc[1]
+	}
+	s.addParagraph(text[prev:], opts.HTML)
+	return s.parts
+}
+
+// splitStream splits a streamed text into chunks like splitDocument, but every sentence becomes a separate chunk,
+// so the first sentences are translated without waiting for the whole paragraph. Texts with markup or placeholders
+// are split only on paragraphs, because sentence boundaries may be inside tags or ICU arguments.
+func splitStream(text string, maxBytes int, opts TranslationOptions) []documentPart {
+	if opts.HTML || opts.Placeholders {
+		return splitDocument(text, maxBytes, opts)
+	}
+	s := documentSplitter{maxBytes: maxBytes}
+	for _, part := range splitDocument(text, maxBytes, TranslationOptions{}) {
+		if !part.translate {
+			s.addSpace(part.text)
+			continue
+		}
+		prev := 0
+		for _, cut := range cutOffsets(part.text, splitSentences) {
+			s.addSentence(part.text[prev:cut])
+			prev = cut
+		}
+		s.addSentence(part.text[prev:])
+	}
+	return s.parts
+}
+
+func (s *documentSplitter) addSentence(text string) {
+	lead, core, trail := trimSpace(text)
+	s.addSpace(lead)
+	s.addChunk(core)
+	s.addSpace(trail)
+}
+
+func (s *documentSplitter) addParagraph(text string, html bool) {
+	if html {
+		s.splitHTML(text)
+		return
+	}
+	s.split(text, splitSentences)
+}
+
+// splitHTML adds HTML paragraph as a chunk if it fits, otherwise splits it on the boundaries outside of elements.
+func (s *documentSplitter) splitHTML(text string) {
+	lead, core, trail := trimSpace(text)
+	s.addSpace(lead)
+	defer s.addSpace(trail)
+
+	cuts := s.outsidePlaceholders(core, htmlCutOffsets(core))
+	for start := 0; start < len(core); {
+		end := chunkEnd(len(core), cuts, start, s.maxBytes)
+		s.addSentence(core[start:end])
+		start = end
+	}
+}
+
+// split adds the text as a chunk if it fits, otherwise splits it on the boundaries of given level
+// and packs as many pieces as possible into every chunk.
+func (s *documentSplitter) split(text string, level splitLevel) {
+	lead, core, trail := trimSpace(text)
+	s.addSpace(lead)
+	defer s.addSpace(trail)
+
+	if len(core) <= s.maxBytes || level > splitRunes {
+		s.addChunk(core)
+		return
+	}
+
+	cuts := s.outsidePlaceholders(core, cutOffsets(core, level))
+	for start := 0; start < len(core); {
+		end := chunkEnd(len(core), cuts, start, s.maxBytes)
+		s.split(core[start:end], level+1)
+		start = end
+	}
+}
+
+// chunkEnd returns the end of the chunk starting at start in the text of given length: the furthest cut fitting
+// into the chunk or the nearest one if there is no such cut. Cuts must be sorted.
+func chunkEnd(length int, cuts []int, start, maxBytes int) int {
+	if length-start <= maxBytes {
+		return length
+	}
+	i, _ := slices.BinarySearch(cuts, start+1)
+	if i == len(cuts) {
+		return length
+	}
+	end := cuts[i]
+	for i++; i < len(cuts) && cuts[i]-start <= maxBytes; i++ {
+		end = cuts[i]
+	}
+	return end
+}
+
+var (
+	// voidElements are HTML elements without end tags.
+	voidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+		"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+	}
+	// blockElements are HTML elements which separate texts, unlike inline elements like <b>.
+	blockElements = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true, "div": true,
+		"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "form": true, "h1": true,
+		"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true,
+		"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true,
+		"td": true, "th": true, "tr": true, "ul": true,
+	}
+)
+
+// htmlTagName matches the name of HTML start or end tag
+var htmlTagName = regexp.MustCompile(`^</?([a-zA-Z][a-zA-Z0-9-]*)`)
+
+// htmlCutOffsets returns offsets which HTML text can be cut at: sentence boundaries and whitespace after
+// block elements, which are not inside any element.
+func htmlCutOffsets(text string) []int {
+	var candidates []int
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		candidates = append(candidates, loc[1])
+	}
+	for _, loc := range wordEnd.FindAllStringIndex(text, -1) {
+		tagStart := strings.LastIndexByte(text[:loc[0]], '<')
+		if loc[0] == 0 || text[loc[0]-1] != '>' || tagStart < 0 {
+			continue
+		}
+		tag := text[tagStart:loc[0]]
+		if m := htmlTagName.FindStringSubmatch(tag); m != nil && blockElements[strings.ToLower(m[1])] {
+			candidates = append(candidates, loc[1])
+		}
+	}
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	var (
+		tags    = htmlTag.FindAllStringIndex(text, -1)
+		offsets []int
+		depth   int
+		next    int
+	)
+	for _, cut := range candidates {
+		for ; next < len(tags) && tags[next][1] <= cut; next++ {
+			tag := text[tags[next][0]:tags[next][1]]
+			m := htmlTagName.FindStringSubmatch(tag)
+			switch {
+			case m == nil || voidElements[strings.ToLower(m[1])] || strings.HasSuffix(tag, "/>"):
+				// comments, doctype and elements without content
+			case strings.HasPrefix(tag, "</"):
+				depth = max(depth-1, 0)
+			default:
+				depth++
+			}
+		}
+		if depth == 0 && (next == len(tags) || tags[next][0] >= cut) && cut < len(text) {
+			offsets = append(offsets, cut)
+		}
+	}
+	return offsets
+}
+
+// cutOffsets returns offsets which the text can be cut at on given level.
+func cutOffsets(text string, level splitLevel) []int {
+	var pattern *regexp.Regexp
+	switch level {
+	case splitSentences:
+		pattern = sentenceEnd
+	case splitWords:
+		pattern = wordEnd
+	default:
+		offsets := make([]int, 0, utf8.RuneCountInString(text))
+		for i := range text {
+			if i != 0 {
+				offsets = append(offsets, i)
+			}
+		}
+		return offsets
+	}
+	var offsets []int
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		offsets = append(offsets, loc[1])
+	}
+	return offsets
+}
+
+// outsidePlaceholders removes offsets inside placeholders of the text from the cuts if the splitter
+// keeps placeholders. Texts are cut only outside of placeholders, so their parts have whole placeholders.
+func (s *documentSplitter) outsidePlaceholders(text string, cuts []int) []int {
+	if !s.placeholders {
+		return cuts
+	}
+	spans := placeholderSpans(text)
+	return slices.DeleteFunc(cuts, func(cut int) bool {
+		return insideSpan(spans, cut)
+	})
+}
+
+// placeholderSpans returns sorted locations of placeholders in the text.
+func placeholderSpans(text string) [][]int {
+	var (
+		spans [][]int
+		pos   int
+	)
+	for _, part := range (messageParser{placeholders: true}).parse(text, false).parts {
+		if part.masked() {
+			spans = append(spans, []int{pos, pos + len(part.text)})
+		}
+		pos += len(part.text)
+	}
+	return spans
+}
+
+// insideSpan reports if the offset is strictly inside one of sorted spans.
+func insideSpan(spans [][]int, offset int) bool {
+	i, _ := slices.BinarySearchFunc(spans, offset, func(span []int, offset int) int {
+		return span[1] - offset
+	})
+	return i < len(spans) && spans[i][0] < offset && offset < spans[i][1]
+}
+
+func (s *documentSplitter) addChunk(text string) {
+	if text == "" {
+		return
+	}
+	s.parts = append(s.parts, documentPart{text: text, translate: true})
+}
+
+func (s *documentSplitter) addSpace(text string) {
+	if text == "" {
+		return
+	}
+	if n := len(s.parts); n != 0 && !s.parts[n-1].translate {
+		s.parts[n-1].text += text
+		return
+	}
+	s.parts = append(s.parts, documentPart{text: text})
+}
+
+// trimSpace splits the text into leading whitespace, the text itself and trailing whitespace.
+func trimSpace(text string) (lead, core, trail string) {
+	core = strings.TrimLeftFunc(text, unicode.IsSpace)
+	lead = text[:len(text)-len(core)]
+	trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+	return lead, trimmed, core[len(trimmed):]
+}
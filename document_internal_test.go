@@ -0,0 +1,177 @@
+package gobergamot
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitDocument_HTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxBytes int
+		expected []string
+	}{
+		{
+			name:     "fitting paragraphs",
+			text:     "<p>Hello. World.</p>\n\n<p>Goodbye.</p>",
+			maxBytes: 100,
+			expected: []string{"<p>Hello. World.</p>", "<p>Goodbye.</p>"},
+		},
+		{
+			name:     "sentences outside of elements",
+			text:     "First <b>sentence</b>. Second one. <i>Third</i> sentence.",
+			maxBytes: 30,
+			expected: []string{"First <b>sentence</b>.", "Second one.", "<i>Third</i> sentence."},
+		},
+		{
+			name:     "elements",
+			text:     "<p>First paragraph. Still first.</p> <p>Second paragraph.</p><br> <p>Third.</p>",
+			maxBytes: 40,
+			expected: []string{"<p>First paragraph. Still first.</p>", "<p>Second paragraph.</p><br>", "<p>Third.</p>"},
+		},
+		{
+			name:     "sentences inside element",
+			text:     "<div>First sentence. Second sentence. Third sentence.</div>",
+			maxBytes: 20,
+			expected: []string{"<div>First sentence. Second sentence. Third sentence.</div>"},
+		},
+		{
+			name:     "greater-than sign",
+			text:     "a > b. <p>c</p> d > e.",
+			maxBytes: 10,
+			expected: []string{"a > b.", "<p>c</p>", "d > e."},
+		},
+		{
+			name:     "sentence end inside tag",
+			text:     `<a title="Hello. World">link</a> text. More text.`,
+			maxBytes: 40,
+			expected: []string{`<a title="Hello. World">link</a> text.`, "More text."},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				chunks []string
+				joined strings.Builder
+			)
+			for _, part := range splitDocument(tc.text, tc.maxBytes, TranslationOptions{HTML: true}) {
+				joined.WriteString(part.text)
+				if part.translate {
+					chunks = append(chunks, part.text)
+				}
+			}
+			if !reflect.DeepEqual(chunks, tc.expected) {
+				t.Errorf("expected chunks %q, got %q", tc.expected, chunks)
+			}
+			if joined.String() != tc.text {
+				t.Errorf("parts %q don't make up the text", joined.String())
+			}
+		})
+	}
+}
+
+func TestSplitDocument_Placeholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxBytes int
+		expected []string
+	}{
+		{
+			name:     "sentences outside of placeholders",
+			text:     "Hello, {name}. You have %d new messages. Bye.",
+			maxBytes: 26,
+			expected: []string{"Hello, {name}.", "You have %d new messages.", "Bye."},
+		},
+		{
+			name:     "sentences inside plural argument",
+			text:     "Done. {count, plural, one {# file. It is saved.} other {# files. They are saved.}} Next.",
+			maxBytes: 20,
+			expected: []string{"Done.", "{count, plural, one {# file. It is saved.} other {# files. They are saved.}}", "Next."},
+		},
+		{
+			name:     "words inside placeholder",
+			text:     "Press {button name} now",
+			maxBytes: 8,
+			expected: []string{"Press", "{button name}", "now"},
+		},
+		{
+			name:     "blank line inside plural argument",
+			text:     "{count, plural,\n\none {# item}\n\nother {# items}}\n\nNext.",
+			maxBytes: 100,
+			expected: []string{"{count, plural,\n\none {# item}\n\nother {# items}}", "Next."},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				chunks []string
+				joined strings.Builder
+			)
+			for _, part := range splitDocument(tc.text, tc.maxBytes, TranslationOptions{Placeholders: true}) {
+				joined.WriteString(part.text)
+				if part.translate {
+					chunks = append(chunks, part.text)
+				}
+			}
+			if !reflect.DeepEqual(chunks, tc.expected) {
+				t.Errorf("expected chunks %q, got %q", tc.expected, chunks)
+			}
+			if joined.String() != tc.text {
+				t.Errorf("parts %q don't make up the text", joined.String())
+			}
+		})
+	}
+}
+
+func TestPool_TranslateDocument_InFlight(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const size = 2
+	p := newTestPool(PoolConfig{
+		Config:        Config{MaxInputBytes: 16},
+		MinSize:       size,
+		MaxSize:       size,
+		MaxQueueDepth: size,
+	})
+	p.workers = size
+
+	// workers "translating" texts to upper case
+	for i := 0; i < size; i++ {
+		go func() {
+			for {
+				select {
+				case <-p.done:
+					return
+				case <-p.queue.ready:
+				}
+				req, ok := p.queue.pop()
+				if !ok {
+					continue
+				}
+				time.Sleep(time.Millisecond)
+				outputs := make([]string, len(req.reqs))
+				for i := range req.reqs {
+					outputs[i] = strings.ToUpper(req.reqs[i].Text)
+				}
+				req.respChan <- workerResponse{outputs: outputs}
+			}
+		}()
+	}
+	defer close(p.done)
+
+	text := strings.Repeat("Hello World.\n\n", 20)
+	// batches exceeding the queue depth would fail with ErrQueueFull
+	output, err := p.TranslateDocument(ctx, TranslationRequest{Text: text})
+	if err != nil {
+		t.Fatalf("failed to translate document: %v", err)
+	}
+	if output != strings.ToUpper(text) {
+		t.Errorf("unexpected output %q", output)
+	}
+}
@@ -0,0 +1,60 @@
+package gobergamot_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/internal/wasm"
+)
+
+func TestTranslator_TranslateDocument(t *testing.T) {
+	ctx := context.Background()
+
+	stderr := new(strings.Builder)
+	translator, err := gobergamot.New(ctx, gobergamot.Config{
+		CompileConfig: wasm.CompileConfig{Stderr: stderr},
+		FilesBundle:   testBundle(t),
+		MaxInputBytes: 64,
+	})
+	if err != nil {
+		t.Fatalf("failed to create translator: %v\n\nstderr: %s", err, stderr.String())
+	}
+	defer func() {
+		if err := translator.Close(ctx); err != nil {
+			t.Fatalf("failed to close translator: %v", err)
+		}
+	}()
+
+	document := "  Hello, World!\n\n\tComputers have become an integral part of our daily lives. " +
+		"They have a great impact on the way we live, work, and communicate.\r\n\r\nComputers have opened up new possibilities.\n"
+
+	_, err = translator.Translate(ctx, gobergamot.TranslationRequest{Text: document})
+	if !errors.Is(err, gobergamot.ErrInputTooLarge) {
+		t.Fatalf("expected ErrInputTooLarge, got %v", err)
+	}
+
+	output, err := translator.TranslateDocument(ctx, gobergamot.TranslationRequest{Text: document})
+	if err != nil {
+		t.Fatalf("failed to translate document: %v\n\nstderr: %s", err, stderr.String())
+	}
+	if !strings.HasPrefix(output, "  Здравствуйте, Мир!\n\n\tКомпьютеры стали неотъемлемой частью нашей повседневной жизни. ") {
+		t.Errorf("unexpected beginning of translation: %q", output)
+	}
+	if !strings.HasSuffix(output, "\r\n\r\nКомпьютеры открыли новые возможности.\n") {
+		t.Errorf("unexpected ending of translation: %q", output)
+	}
+	if strings.Count(output, "\n\n") != 1 || strings.Count(output, "\r\n\r\n") != 1 {
+		t.Errorf("paragraph separators are not preserved: %q", output)
+	}
+
+	output, err = translator.TranslateDocument(ctx, gobergamot.TranslationRequest{Text: " \n\n "})
+	if err != nil {
+		t.Fatalf("failed to translate whitespace: %v", err)
+	}
+	if output != " \n\n " {
+		t.Errorf("expected whitespace to be kept as is, got %q", output)
+	}
+}
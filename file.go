@@ -2,53 +2,79 @@ package gobergamot
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+
+	"github.com/KSpaceer/gobergamot/internal/zstd"
 )
 
+type compression int
+
+const (
+	compressionNone compression = iota
+	compressionGzip
+	compressionZstd
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// gzipMinSize is a size of gzip header and trailer
+const gzipMinSize = 18
+
+func detectCompression(header []byte) compression {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return compressionGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return compressionZstd
+	default:
+		return compressionNone
+	}
+}
+
 type alignedMemoryFile struct {
 	Reader    io.Reader
 	Alignment uint
-}
 
-type readerWithLen interface {
-	io.Reader
-	Len() int
+	// compression of the file data, detected when getting the file size
+	compression compression
 }
 
-var _ readerWithLen = (*bytes.Buffer)(nil)
-
+// size returns size of the file data. If the data is compressed, size of uncompressed data is returned.
 func (f *alignedMemoryFile) size() (uint32, error) {
-	var size uint64
+	var (
+		size uint64
+		err  error
+	)
 	if f.Reader == nil {
 		return 0, errors.New("reader is nil")
 	}
 
 	switch reader := f.Reader.(type) {
-	case readerWithLen:
-		size = uint64(reader.Len())
-	case io.Seeker:
-		streamLen, err := reader.Seek(0, io.SeekEnd)
-		if err != nil {
-			return 0, err
-		}
-		if _, err := reader.Seek(0, io.SeekStart); err != nil {
-			return 0, err
-		}
-		size = uint64(streamLen)
+	case readerWithBytes:
+		size, err = f.sizeFromBytes(reader.Bytes())
+	case io.ReadSeeker:
+		size, err = f.sizeFromSeeker(reader)
 	default:
 		// We have failed to avoid copying the data into memory to get the size...
 		// git commit sudoku
 		buf := new(bytes.Buffer)
-		bufLen, err := io.Copy(buf, reader)
-		if err != nil {
+		if _, err := io.Copy(buf, reader); err != nil {
 			return 0, err
 		}
 		// reader's been read, point it at buf now.
 		f.Reader = buf
-		size = uint64(bufLen)
+		size, err = f.sizeFromBytes(buf.Bytes())
+	}
+	if err != nil {
+		return 0, err
 	}
 
 	if size > math.MaxUint32 {
@@ -57,6 +83,102 @@ func (f *alignedMemoryFile) size() (uint32, error) {
 	return uint32(size), nil
 }
 
+func (f *alignedMemoryFile) sizeFromBytes(data []byte) (uint64, error) {
+	f.compression = detectCompression(data)
+	switch f.compression {
+	case compressionGzip:
+		if len(data) < gzipMinSize {
+			return 0, io.ErrUnexpectedEOF
+		}
+		// gzip trailer ends with size of uncompressed data modulo 2^32
+		return uint64(binary.LittleEndian.Uint32(data[len(data)-4:])), nil
+	case compressionZstd:
+		size, known, err := zstd.ContentSize(data)
+		if err != nil || known {
+			return size, err
+		}
+		// content size is optional in zstd frames, so the data is decompressed to get it
+		buf := new(bytes.Buffer)
+		if _, err := io.Copy(buf, zstd.NewReader(bytes.NewReader(data))); err != nil {
+			return 0, err
+		}
+		f.Reader, f.compression = buf, compressionNone
+		return uint64(buf.Len()), nil
+	default:
+		return uint64(len(data)), nil
+	}
+}
+
+func (f *alignedMemoryFile) sizeFromSeeker(reader io.ReadSeeker) (uint64, error) {
+	header := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(reader, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	f.compression = detectCompression(header[:n])
+
+	var size uint64
+	switch f.compression {
+	case compressionGzip:
+		streamLen, err := reader.Seek(-4, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		if streamLen+4 < gzipMinSize {
+			return 0, io.ErrUnexpectedEOF
+		}
+		trailer := make([]byte, 4)
+		if _, err := io.ReadFull(reader, trailer); err != nil {
+			return 0, err
+		}
+		// gzip trailer ends with size of uncompressed data modulo 2^32
+		size = uint64(binary.LittleEndian.Uint32(trailer))
+	case compressionZstd:
+		// frame headers are spread over the data, so reading it all to find them
+		if _, err := reader.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		buf := new(bytes.Buffer)
+		if _, err := io.Copy(buf, reader); err != nil {
+			return 0, err
+		}
+		f.Reader = buf
+		return f.sizeFromBytes(buf.Bytes())
+	default:
+		streamLen, err := reader.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		size = uint64(streamLen)
+	}
+
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// decompressedReader returns reader of the uncompressed file data.
+// If the file provides its bytes, they are not consumed.
+func (f *alignedMemoryFile) decompressedReader() (io.ReadCloser, error) {
+	r := f.Reader
+	if bytesProvider, ok := r.(readerWithBytes); ok {
+		r = bytes.NewReader(bytesProvider.Bytes())
+	}
+	switch f.compression {
+	case compressionGzip:
+		gzipReader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return gzipReader, nil
+	case compressionZstd:
+		return io.NopCloser(zstd.NewReader(r)), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
 type readerWithBytes interface {
 	io.Reader
 	Bytes() []byte
@@ -0,0 +1,54 @@
+package gobergamot
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot/internal/zstd"
+)
+
+func TestAlignedMemoryFile_Zstd(t *testing.T) {
+	for _, name := range []string{
+		"internal/zstd/testdata/1d43ea62.words.zst",
+		"internal/zstd/testdata/1d43ea62.words-no-content-size.zst",
+		"internal/zstd/testdata/70fa686d.words-2-frames.zst",
+	} {
+		compressed, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := io.ReadAll(zstd.NewReader(bytes.NewReader(compressed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for kind, reader := range map[string]func() io.Reader{
+			"buffer": func() io.Reader { return bytes.NewBuffer(compressed) },
+			"seeker": func() io.Reader { return bytes.NewReader(compressed) },
+			"reader": func() io.Reader { return io.MultiReader(bytes.NewReader(compressed)) },
+		} {
+			file := &alignedMemoryFile{Reader: reader()}
+			size, err := file.size()
+			if err != nil {
+				t.Fatalf("%s %s: failed to get size: %v", name, kind, err)
+			}
+			if int(size) != len(want) {
+				t.Fatalf("%s %s: expected size %d, got %d", name, kind, len(want), size)
+			}
+			view := make([]byte, size)
+			if file.compression == compressionNone {
+				_, err = io.ReadFull(file.Reader, view)
+			} else {
+				err = decompressIntoByteArrayView(view, file, size)
+			}
+			if err != nil {
+				t.Fatalf("%s %s: failed to decompress: %v", name, kind, err)
+			}
+			if !bytes.Equal(view, want) {
+				t.Errorf("%s %s: decompressed data differs", name, kind)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package gobergamot
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrEmptyGlossaryTerm = errors.New("glossary term is empty")
+
+// GlossaryEntry defines translation of a term.
+type GlossaryEntry struct {
+	// Source term. It is matched case-sensitively as a whole word or phrase.
+	Source string
+	// Target is a fixed translation of the term. If it is empty, the term is not translated at all.
+	Target string
+}
+
+// Glossary enforces translations of terms, e.g. brand and product names. Terms are protected
+// like placeholders (see TranslationOptions.Placeholders) and substituted after translation,
+// so if the model loses a term, PlaceholderLostError is returned.
+//
+// Glossary is immutable and safe for concurrent use.
+type Glossary struct {
+	entries []GlossaryEntry
+	// index of the entry by its source term
+	bySource map[string]int
+	// pattern matches any of the source terms, longer terms are matched first
+	pattern *regexp.Regexp
+}
+
+// NewGlossary creates a glossary with the entries. If entries have the same source term, the first one is used.
+func NewGlossary(entries ...GlossaryEntry) (*Glossary, error) {
+	g := &Glossary{bySource: make(map[string]int, len(entries))}
+	for _, entry := range entries {
+		if entry.Source == "" {
+			return nil, ErrEmptyGlossaryTerm
+		}
+		if _, ok := g.bySource[entry.Source]; ok {
+			continue
+		}
+		g.bySource[entry.Source] = len(g.entries)
+		g.entries = append(g.entries, entry)
+	}
+
+	terms := make([]string, 0, len(g.entries))
+	for _, entry := range g.entries {
+		terms = append(terms, regexp.QuoteMeta(entry.Source))
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		return len(terms[i]) > len(terms[j])
+	})
+	if len(terms) > 0 {
+		g.pattern = regexp.MustCompile(strings.Join(terms, "|"))
+	}
+	return g, nil
+}
+
+// Entries returns the entries of the glossary.
+func (g *Glossary) Entries() []GlossaryEntry {
+	return append([]GlossaryEntry(nil), g.entries...)
+}
+
+// find returns ranges of the terms in the text. Terms are matched only at word boundaries.
+func (g *Glossary) find(text string) [][]int {
+	if g == nil || g.pattern == nil {
+		return nil
+	}
+	var locs [][]int
+	for _, loc := range g.pattern.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(text[loc[1]:])
+		if isWordRune(before) || isWordRune(after) {
+			continue
+		}
+		locs = append(locs, loc)
+	}
+	return locs
+}
+
+func (g *Glossary) entry(source string) GlossaryEntry {
+	return g.entries[g.bySource[source]]
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// LoadGlossaryCSV reads glossary entries from CSV with source terms in the first column and target terms
+// in the second one. Rows with a single column or an empty target term define terms which are not translated.
+// The first row is skipped if it is a "source,target" header.
+func LoadGlossaryCSV(r io.Reader) (*Glossary, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []GlossaryEntry
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read glossary: %w", err)
+		}
+		if line == 1 && len(record) >= 2 &&
+			strings.EqualFold(record[0], "source") && strings.EqualFold(record[1], "target") {
+			continue
+		}
+		if len(record) == 0 || (len(record) == 1 && record[0] == "") {
+			continue
+		}
+		entry := GlossaryEntry{Source: record[0]}
+		if len(record) > 1 {
+			entry.Target = record[1]
+		}
+		if entry.Source == "" {
+			return nil, fmt.Errorf("line %d: %w", line, ErrEmptyGlossaryTerm)
+		}
+		entries = append(entries, entry)
+	}
+	return NewGlossary(entries...)
+}
+
+// LoadGlossaryTBX reads glossary entries of the language pair from TBX (TermBase eXchange) document.
+// Every term of the source language becomes an entry with the first term of the target language.
+// Concept entries without target language terms are skipped. Language codes match regional variants,
+// e.g. "en" matches "en-US".
+func LoadGlossaryTBX(r io.Reader, from, to string) (*Glossary, error) {
+	dec := xml.NewDecoder(r)
+	var (
+		entries []GlossaryEntry
+		// terms of the current concept entry
+		sourceTerms, targetTerms []string
+		// language of the current langSet
+		lang string
+		term strings.Builder
+		// set inside term element
+		inTerm bool
+	)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read glossary: %w", err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "termEntry", "conceptEntry":
+				sourceTerms, targetTerms = nil, nil
+			case "langSet", "langSec":
+				lang = ""
+				for _, attr := range tok.Attr {
+					if attr.Name.Local == "lang" {
+						lang = attr.Value
+					}
+				}
+			case "term":
+				inTerm = true
+				term.Reset()
+			}
+		case xml.CharData:
+			if inTerm {
+				term.Write(tok)
+			}
+		case xml.EndElement:
+			switch tok.Name.Local {
+			case "term":
+				inTerm = false
+				text := strings.TrimSpace(term.String())
+				switch {
+				case text == "":
+				case matchLanguage(lang, from):
+					sourceTerms = append(sourceTerms, text)
+				case matchLanguage(lang, to):
+					targetTerms = append(targetTerms, text)
+				}
+			case "termEntry", "conceptEntry":
+				if len(targetTerms) == 0 {
+					continue
+				}
+				for _, source := range sourceTerms {
+					entries = append(entries, GlossaryEntry{Source: source, Target: targetTerms[0]})
+				}
+			}
+		}
+	}
+	return NewGlossary(entries...)
+}
+
+// matchLanguage reports if the language tag is the code or its regional variant.
+func matchLanguage(tag, code string) bool {
+	tag, code = strings.ToLower(tag), strings.ToLower(code)
+	return tag == code || strings.HasPrefix(tag, code+"-") || strings.HasPrefix(tag, code+"_")
+}
@@ -0,0 +1,120 @@
+package gobergamot_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/internal/wasm"
+)
+
+func TestLoadGlossaryCSV(t *testing.T) {
+	glossary, err := gobergamot.LoadGlossaryCSV(strings.NewReader(
+		"source,target\nFirefox\ngobergamot,\nWorld, Мир\n\"Hello, World\",Привет\nFirefox,Огнелис\n",
+	))
+	if err != nil {
+		t.Fatalf("failed to load glossary: %v", err)
+	}
+	want := []gobergamot.GlossaryEntry{
+		{Source: "Firefox"},
+		{Source: "gobergamot"},
+		{Source: "World", Target: "Мир"},
+		{Source: "Hello, World", Target: "Привет"},
+	}
+	if entries := glossary.Entries(); !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected %+v, got %+v", want, entries)
+	}
+
+	_, err = gobergamot.LoadGlossaryCSV(strings.NewReader("Firefox\n,Мир\n"))
+	if !errors.Is(err, gobergamot.ErrEmptyGlossaryTerm) {
+		t.Errorf("expected ErrEmptyGlossaryTerm, got %v", err)
+	}
+}
+
+func TestLoadGlossaryTBX(t *testing.T) {
+	const tbx = `<?xml version="1.0" encoding="UTF-8"?>
+<martif type="TBX" xml:lang="en">
+  <text>
+    <body>
+      <termEntry id="1">
+        <langSet xml:lang="en-US">
+          <tig><term>web browser</term></tig>
+          <tig><term>browser</term></tig>
+        </langSet>
+        <langSet xml:lang="ru">
+          <ntig><termGrp><term>веб-браузер</term></termGrp></ntig>
+        </langSet>
+      </termEntry>
+      <termEntry id="2">
+        <langSet xml:lang="en"><tig><term>tab</term></tig></langSet>
+        <langSet xml:lang="de"><tig><term>Tab</term></tig></langSet>
+      </termEntry>
+    </body>
+  </text>
+</martif>`
+	glossary, err := gobergamot.LoadGlossaryTBX(strings.NewReader(tbx), "en", "ru")
+	if err != nil {
+		t.Fatalf("failed to load glossary: %v", err)
+	}
+	want := []gobergamot.GlossaryEntry{
+		{Source: "web browser", Target: "веб-браузер"},
+		{Source: "browser", Target: "веб-браузер"},
+	}
+	if entries := glossary.Entries(); !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected %+v, got %+v", want, entries)
+	}
+}
+
+func TestTranslator_Glossary(t *testing.T) {
+	ctx := context.Background()
+
+	glossary, err := gobergamot.NewGlossary(
+		gobergamot.GlossaryEntry{Source: "Firefox"},
+		gobergamot.GlossaryEntry{Source: "computers", Target: "ЭВМ"},
+	)
+	if err != nil {
+		t.Fatalf("failed to create glossary: %v", err)
+	}
+	stderr := new(strings.Builder)
+	translator, err := gobergamot.New(ctx, gobergamot.Config{
+		CompileConfig: wasm.CompileConfig{Stderr: stderr},
+		FilesBundle:   testBundle(t),
+		Glossary:      glossary,
+	})
+	if err != nil {
+		t.Fatalf("failed to create translator: %v\n\nstderr: %s", err, stderr.String())
+	}
+	defer func() {
+		if err := translator.Close(ctx); err != nil {
+			t.Fatalf("failed to close translator: %v", err)
+		}
+	}()
+
+	result, err := translator.TranslateDetailed(ctx, gobergamot.TranslationRequest{
+		Text: "Firefox runs on many computers.",
+	})
+	if err != nil {
+		t.Fatalf("failed to translate: %v\n\nstderr: %s", err, stderr.String())
+	}
+	if !strings.Contains(result.Text, "Firefox") || !strings.Contains(result.Text, "ЭВМ") {
+		t.Errorf("glossary terms are not applied: %q", result.Text)
+	}
+	if len(result.Glossary) != 2 {
+		t.Errorf("expected 2 applied glossary entries, got %+v", result.Glossary)
+	}
+
+	// glossary of the request overrides the one of the translator
+	output, err := translator.Translate(ctx, gobergamot.TranslationRequest{
+		Text:    "Hello, World!",
+		Options: gobergamot.TranslationOptions{Glossary: &gobergamot.Glossary{}},
+	})
+	if err != nil {
+		t.Fatalf("failed to translate: %v", err)
+	}
+	if output != "Здравствуйте, Мир!" {
+		t.Errorf("unexpected translation: %q", output)
+	}
+}
@@ -0,0 +1,119 @@
+// Package i18n provides batch translation of localization strings. Its subpackages translate
+// catalogs of particular formats.
+package i18n
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/internal/errgroup"
+)
+
+// DefaultBatchSize is a default number of texts translated with a single TranslateMultiple call.
+const DefaultBatchSize = 32
+
+// Translator translates texts. Both gobergamot.Translator and gobergamot.Pool implement it,
+// gobergamot.Registry does too if Options.From and Options.To are set.
+type Translator interface {
+	TranslateMultiple(ctx context.Context, requests ...gobergamot.TranslationRequest) ([]string, error)
+}
+
+// Options define how texts are translated.
+type Options struct {
+	// From and To are codes of source and target languages set in translation requests.
+	// They are required only for gobergamot.Registry.
+	From, To string
+
+	// TranslationOptions are options of every translation request.
+	TranslationOptions gobergamot.TranslationOptions
+
+	// BatchSize is a number of texts translated with a single TranslateMultiple call.
+	// DefaultBatchSize is used if it is not positive.
+	BatchSize int
+
+	// Parallelism is a number of concurrent TranslateMultiple calls. Values greater than 1 make sense
+	// only for gobergamot.Pool, e.g. its size. Texts are translated sequentially if it is not positive.
+	Parallelism int
+}
+
+// Translate translates the texts in batches and returns translations in the same order.
+// Duplicate texts are translated once, empty texts are not translated at all.
+func Translate(ctx context.Context, translator Translator, texts []string, opts Options) ([]string, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	parallelism := max(opts.Parallelism, 1)
+
+	var (
+		indices = make(map[string]int)
+		unique  []string
+	)
+	for _, text := range texts {
+		if _, ok := indices[text]; ok || text == "" {
+			continue
+		}
+		indices[text] = len(unique)
+		unique = append(unique, text)
+	}
+
+	// stopping translation of other batches if one fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		translated = make([]string, len(unique))
+		sem        = make(chan struct{}, parallelism)
+		eg         = errgroup.New()
+	)
+	for start := 0; start < len(unique); start += batchSize {
+		batch := unique[start:min(start+batchSize, len(unique))]
+		requests := make([]gobergamot.TranslationRequest, len(batch))
+		for i := range batch {
+			requests[i] = gobergamot.TranslationRequest{
+				Text:    batch[i],
+				From:    opts.From,
+				To:      opts.To,
+				Options: opts.TranslationOptions,
+			}
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		start := start
+		eg.Go(func() error {
+			defer func() { <-sem }()
+			outputs, err := translator.TranslateMultiple(ctx, requests...)
+			if err == nil && len(outputs) != len(requests) {
+				err = fmt.Errorf("expected %d translated texts but got %d", len(requests), len(outputs))
+			}
+			if err != nil {
+				cancel()
+				return err
+			}
+			copy(translated[start:], outputs)
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	// the loop could be interrupted by cancellation of the parent context
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	output := make([]string, len(texts))
+	for i, text := range texts {
+		if text != "" {
+			output[i] = translated[indices[text]]
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,61 @@
+package i18n_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/i18n"
+)
+
+// upperTranslator "translates" texts by converting them to upper case and records batch sizes.
+type upperTranslator struct {
+	mu      sync.Mutex
+	batches []int
+	err     error
+}
+
+func (t *upperTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	t.mu.Lock()
+	t.batches = append(t.batches, len(requests))
+	t.mu.Unlock()
+	if t.err != nil {
+		return nil, t.err
+	}
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		outputs[i] = strings.ToUpper(requests[i].Text)
+	}
+	return outputs, nil
+}
+
+func TestTranslate(t *testing.T) {
+	ctx := context.Background()
+	translator := &upperTranslator{}
+
+	texts := []string{"one", "two", "", "one", "three", "four", "five"}
+	output, err := i18n.Translate(ctx, translator, texts, i18n.Options{BatchSize: 2, Parallelism: 2})
+	if err != nil {
+		t.Fatalf("failed to translate: %v", err)
+	}
+	want := []string{"ONE", "TWO", "", "ONE", "THREE", "FOUR", "FIVE"}
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("expected %q, got %q", want, output)
+	}
+	// 5 unique non-empty texts in batches of 2
+	if len(translator.batches) != 3 {
+		t.Errorf("expected 3 batches, got %v", translator.batches)
+	}
+
+	translator = &upperTranslator{err: errors.New("failed")}
+	if _, err := i18n.Translate(ctx, translator, texts, i18n.Options{}); !errors.Is(err, translator.err) {
+		t.Errorf("expected translator error, got %v", err)
+	}
+}
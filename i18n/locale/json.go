@@ -0,0 +1,122 @@
+package locale
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseJSON parses JSON into a yaml.v3 document node, keeping order of object keys.
+func parseJSON(data []byte) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := readJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after JSON value at offset %d", dec.InputOffset())
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{value}}, nil
+}
+
+func readJSONValue(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if tok == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for dec.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		// closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tok}, nil
+	case json.Number:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: tok.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(tok)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// writeJSON writes the node parsed by parseJSON. Values are written on separate lines
+// unless indent is empty.
+func writeJSON(buf *bytes.Buffer, node *yaml.Node, indent string, depth int) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		openDelim, closeDelim := byte('['), byte(']')
+		step := 1
+		if node.Kind == yaml.MappingNode {
+			openDelim, closeDelim = '{', '}'
+			step = 2
+		}
+		buf.WriteByte(openDelim)
+		for i := 0; i < len(node.Content); i += step {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONIndent(buf, indent, depth+1)
+			if step == 2 {
+				writeJSONString(buf, node.Content[i].Value)
+				buf.WriteByte(':')
+				if indent != "" {
+					buf.WriteByte(' ')
+				}
+			}
+			writeJSON(buf, node.Content[i+step-1], indent, depth+1)
+		}
+		if len(node.Content) > 0 {
+			writeJSONIndent(buf, indent, depth)
+		}
+		buf.WriteByte(closeDelim)
+	default:
+		if node.ShortTag() == "!!str" {
+			writeJSONString(buf, node.Value)
+		} else {
+			buf.WriteString(node.Value)
+		}
+	}
+}
+
+func writeJSONIndent(buf *bytes.Buffer, indent string, depth int) {
+	if indent != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Repeat(indent, depth))
+	}
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	// encoding a string never fails
+	_ = enc.Encode(s)
+	// removing the line break written by Encode
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,260 @@
+// Package locale translates nested JSON, YAML and TOML locale files used by i18next, vue-i18n, go-i18n
+// and similar libraries.
+package locale
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/KSpaceer/gobergamot/i18n"
+)
+
+// Format is a format of locale files.
+type Format int
+
+const (
+	JSON Format = iota + 1
+	YAML
+	TOML
+)
+
+func (f Format) String() string {
+	switch f {
+	case JSON:
+		return "json"
+	case YAML:
+		return "yaml"
+	case TOML:
+		return "toml"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+var ErrUnknownFormat = errors.New("unknown locale file format")
+
+// FormatOf returns the format of the file by its extension.
+func FormatOf(name string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return JSON, nil
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".toml":
+		return TOML, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+}
+
+// File is a locale file. Values are kept as yaml.v3 nodes, so key order and YAML comments and styles
+// are preserved. TOML files are written by replacing translated values in the source.
+type File struct {
+	format Format
+	doc    *yaml.Node
+	// source and string values of TOML file
+	source  []byte
+	strings []tomlString
+	// indentation of nested values
+	indent string
+	// set if the file ends with a line break
+	trailingNewline bool
+}
+
+// Parse parses a locale file of the format.
+func Parse(r io.Reader, format Format) (*File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{
+		format:          format,
+		indent:          detectIndent(data),
+		trailingNewline: bytes.HasSuffix(data, []byte("\n")),
+	}
+	switch format {
+	case JSON:
+		f.doc, err = parseJSON(data)
+	case YAML:
+		f.doc = new(yaml.Node)
+		err = yaml.Unmarshal(data, f.doc)
+	case TOML:
+		f.source = data
+		f.doc, f.strings, err = parseTOML(data)
+	default:
+		err = fmt.Errorf("%w: %v", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %v locale file: %w", format, err)
+	}
+	return f, nil
+}
+
+// Format returns the format of the file.
+func (f *File) Format() Format {
+	return f.format
+}
+
+// Lookup returns the string value at the path of keys. Items of lists are addressed by their indices.
+func (f *File) Lookup(path ...string) (string, bool) {
+	var value string
+	found := false
+	f.walk(func(p []string, node *yaml.Node) {
+		if !found && slices.Equal(p, path) {
+			value, found = node.Value, true
+		}
+	})
+	return value, found
+}
+
+// Translate replaces string values with their translations, keys are not translated.
+// Interpolation tokens like {{name}}, {name}, %s, $t(key), @:key and HTML tags are kept as is.
+// If a token is lost in translation, gobergamot.PlaceholderLostError is returned and the file is left unchanged.
+//
+// If existing file is not nil, non-empty values of the same keys are copied from it instead of being translated,
+// so only new keys of the source file are translated. Keys which are absent in the source file are dropped.
+// Returns a number of translated values.
+func (f *File) Translate(ctx context.Context, translator i18n.Translator, opts i18n.Options, existing *File) (int, error) {
+	var existingValues map[string]string
+	if existing != nil {
+		existingValues = make(map[string]string)
+		existing.walk(func(path []string, node *yaml.Node) {
+			if node.Value != "" {
+				existingValues[pathKey(path)] = node.Value
+			}
+		})
+	}
+
+	var (
+		// values with interpolation tokens are translated in HTML mode
+		plainNodes, maskedNodes []*yaml.Node
+		plainTexts, maskedTexts []string
+		tokens                  [][]string
+		// existing values are applied with translations, so the file is not changed if translation fails
+		existingNodes []*yaml.Node
+		kept          []string
+	)
+	f.walk(func(path []string, node *yaml.Node) {
+		if value, ok := existingValues[pathKey(path)]; ok {
+			existingNodes = append(existingNodes, node)
+			kept = append(kept, value)
+			return
+		}
+		if strings.TrimSpace(node.Value) == "" {
+			return
+		}
+		masked, nodeTokens := maskTokens(node.Value)
+		if len(nodeTokens) == 0 {
+			plainNodes = append(plainNodes, node)
+			plainTexts = append(plainTexts, node.Value)
+			return
+		}
+		maskedNodes = append(maskedNodes, node)
+		maskedTexts = append(maskedTexts, masked)
+		tokens = append(tokens, nodeTokens)
+	})
+
+	translated, err := i18n.Translate(ctx, translator, plainTexts, opts)
+	if err != nil {
+		return 0, err
+	}
+	opts.TranslationOptions.HTML = true
+	translatedMasked, err := i18n.Translate(ctx, translator, maskedTexts, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	unmasked := make([]string, len(maskedNodes))
+	for i, node := range maskedNodes {
+		if unmasked[i], err = unmaskTokens(translatedMasked[i], tokens[i], node.Value); err != nil {
+			return 0, err
+		}
+	}
+	for i, node := range existingNodes {
+		node.Value = kept[i]
+	}
+	for i, node := range plainNodes {
+		node.Value = translated[i]
+	}
+	for i, node := range maskedNodes {
+		node.Value = unmasked[i]
+	}
+	return len(plainNodes) + len(maskedNodes), nil
+}
+
+// WriteTo writes the file in its format. JSON files are written with the original indentation,
+// TOML files keep the original formatting except for the translated values.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	switch {
+	case f.format == TOML:
+		writeTOML(&buf, f.source, f.strings)
+	case f.doc.Kind == 0:
+		// empty file
+	case f.format == JSON:
+		writeJSON(&buf, f.doc.Content[0], f.indent, 0)
+		if f.trailingNewline {
+			buf.WriteByte('\n')
+		}
+	default:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(max(len(f.indent), 2))
+		if err := enc.Encode(f.doc); err != nil {
+			return 0, err
+		}
+		if err := enc.Close(); err != nil {
+			return 0, err
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+// walk calls fn for every string value of the file with the path of keys to the value.
+func (f *File) walk(fn func(path []string, node *yaml.Node)) {
+	var visit func(path []string, node *yaml.Node)
+	visit = func(path []string, node *yaml.Node) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				visit(path, child)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				visit(append(path, node.Content[i].Value), node.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				visit(append(path, fmt.Sprint(i)), child)
+			}
+		case yaml.ScalarNode:
+			if node.ShortTag() == "!!str" {
+				fn(path[:len(path):len(path)], node)
+			}
+		}
+	}
+	visit(nil, f.doc)
+}
+
+func pathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// detectIndent returns indentation of the first indented line.
+func detectIndent(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) == len(line) || len(bytes.TrimSpace(trimmed)) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		return string(line[:len(line)-len(trimmed)])
+	}
+	return ""
+}
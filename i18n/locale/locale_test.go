@@ -0,0 +1,407 @@
+package locale_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/i18n"
+	"github.com/KSpaceer/gobergamot/i18n/locale"
+)
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// upperTranslator "translates" texts by converting them to upper case, keeping HTML tags in HTML mode.
+type upperTranslator struct {
+	texts []string
+}
+
+func (t *upperTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		text := requests[i].Text
+		t.texts = append(t.texts, text)
+		if !requests[i].Options.HTML {
+			outputs[i] = strings.ToUpper(text)
+			continue
+		}
+		var (
+			b    strings.Builder
+			last int
+		)
+		for _, loc := range htmlTag.FindAllStringIndex(text, -1) {
+			b.WriteString(strings.ToUpper(text[last:loc[0]]))
+			b.WriteString(text[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		b.WriteString(strings.ToUpper(text[last:]))
+		outputs[i] = b.String()
+	}
+	return outputs, nil
+}
+
+func TestFile_Translate(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   locale.Format
+		source   string
+		existing string
+		want     string
+		texts    int
+	}{
+		{
+			name:   "JSON",
+			format: locale.JSON,
+			source: `{
+    "title": "Welcome",
+    "nested": {
+        "greeting": "Hello, {{name}}!",
+        "count": "%d files & <b>{count}</b> folders",
+        "empty": ""
+    },
+    "list": ["one", 2, true, null],
+    "limit": 1.50
+}
+`,
+			want: `{
+    "title": "WELCOME",
+    "nested": {
+        "greeting": "HELLO, {{name}}!",
+        "count": "%d FILES & <b>{count}</b> FOLDERS",
+        "empty": ""
+    },
+    "list": [
+        "ONE",
+        2,
+        true,
+        null
+    ],
+    "limit": 1.50
+}
+`,
+			texts: 4,
+		},
+		{
+			name:     "JSON incremental",
+			format:   locale.JSON,
+			source:   `{"a":"first","b":{"c":"second","d":"third"}}`,
+			existing: `{"b":{"d":"третий","removed":"удалено"},"a":""}`,
+			want:     `{"a":"FIRST","b":{"c":"SECOND","d":"третий"}}`,
+			texts:    2,
+		},
+		{
+			name:   "YAML",
+			format: locale.YAML,
+			source: `# Application strings
+app:
+  # shown on the main page
+  title: Welcome # inline comment
+  greeting: "Hello, {name}!"
+  items:
+    - first
+    - second
+  enabled: true
+`,
+			existing: `app:
+  title: Добро пожаловать
+`,
+			want: `# Application strings
+app:
+  # shown on the main page
+  title: Добро пожаловать # inline comment
+  greeting: "HELLO, {name}!"
+  items:
+    - FIRST
+    - SECOND
+  enabled: true
+`,
+			texts: 3,
+		},
+		{
+			name:   "TOML",
+			format: locale.TOML,
+			source: `# go-i18n messages
+title = "Welcome" # inline comment
+"quoted key" = 'C:\Users'
+
+[PersonCats]
+description = "The number of cats a person has"
+one = "{{.Name}} has {{.Count}} cat."
+other = """
+{{.Name}} has \
+  {{.Count}} cats."""
+
+[menu]
+items = ["open", 'save', 3]
+link.label = "Tab\tseparated \"quoted\""
+button = { text = "Cancel", width = 1.5 }
+updated = 1979-05-27 07:32:00Z
+enabled = true
+
+[[pages]]
+name = "home"
+`,
+			existing: `[PersonCats]
+one = "{{.Name}} tiene {{.Count}} gato."
+`,
+			want: `# go-i18n messages
+title = "WELCOME" # inline comment
+"quoted key" = "C:\\USERS"
+
+[PersonCats]
+description = "THE NUMBER OF CATS A PERSON HAS"
+one = "{{.Name}} tiene {{.Count}} gato."
+other = "{{.Name}} HAS {{.Count}} CATS."
+
+[menu]
+items = ["OPEN", "SAVE", 3]
+link.label = "TAB\tSEPARATED \"QUOTED\""
+button = { text = "CANCEL", width = 1.5 }
+updated = 1979-05-27 07:32:00Z
+enabled = true
+
+[[pages]]
+name = "HOME"
+`,
+			texts: 9,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := locale.Parse(strings.NewReader(tc.source), tc.format)
+			if err != nil {
+				t.Fatalf("failed to parse file: %v", err)
+			}
+			var existing *locale.File
+			if tc.existing != "" {
+				existing, err = locale.Parse(strings.NewReader(tc.existing), tc.format)
+				if err != nil {
+					t.Fatalf("failed to parse existing file: %v", err)
+				}
+			}
+
+			translator := &upperTranslator{}
+			n, err := file.Translate(context.Background(), translator, i18n.Options{}, existing)
+			if err != nil {
+				t.Fatalf("failed to translate file: %v", err)
+			}
+			if n != tc.texts || len(translator.texts) != tc.texts {
+				t.Errorf("expected %d translated values, got %d: %q", tc.texts, n, translator.texts)
+			}
+
+			var b strings.Builder
+			if _, err := file.WriteTo(&b); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			if b.String() != tc.want {
+				t.Errorf("unexpected file:\n%s", b.String())
+			}
+		})
+	}
+}
+
+func TestFile_Lookup(t *testing.T) {
+	file, err := locale.Parse(strings.NewReader(`{"a": {"b": ["x", "y"]}}`), locale.JSON)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	if value, ok := file.Lookup("a", "b", "1"); !ok || value != "y" {
+		t.Errorf("expected %q, got %q (found %t)", "y", value, ok)
+	}
+	if _, ok := file.Lookup("a", "c"); ok {
+		t.Error("expected missing key to be not found")
+	}
+}
+
+// tagDroppingTranslator "translates" texts by removing HTML tags.
+type tagDroppingTranslator struct{}
+
+func (tagDroppingTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		outputs[i] = htmlTag.ReplaceAllString(requests[i].Text, "")
+	}
+	return outputs, nil
+}
+
+func TestFile_Translate_LostToken(t *testing.T) {
+	source := `{"greeting":"Hello, {name}!","title":"Welcome"}`
+	file, err := locale.Parse(strings.NewReader(source), locale.JSON)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	existing, err := locale.Parse(strings.NewReader(`{"title":"Bienvenido"}`), locale.JSON)
+	if err != nil {
+		t.Fatalf("failed to parse existing file: %v", err)
+	}
+	_, err = file.Translate(context.Background(), tagDroppingTranslator{}, i18n.Options{}, existing)
+	var lostErr *gobergamot.PlaceholderLostError
+	if !errors.As(err, &lostErr) {
+		t.Fatalf("expected PlaceholderLostError, got %v", err)
+	}
+	if lostErr.Placeholder != "{name}" || lostErr.Text != "Hello, {name}!" {
+		t.Errorf("unexpected error %v", lostErr)
+	}
+
+	var b strings.Builder
+	if _, err := file.WriteTo(&b); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if b.String() != source {
+		t.Errorf("expected file to be unchanged, got %s", b.String())
+	}
+}
+
+func TestParse_TOML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		path   []string
+		want   string
+	}{
+		{name: "bare key", source: `key_1-a = "value"`, path: []string{"key_1-a"}, want: "value"},
+		{name: "quoted key", source: `"a.b c" = "value"`, path: []string{"a.b c"}, want: "value"},
+		{name: "literal quoted key", source: `'a\b' = "value"`, path: []string{`a\b`}, want: "value"},
+		{name: "dotted key", source: `a . "b.c" . 'd' = "value"`, path: []string{"a", "b.c", "d"}, want: "value"},
+		{name: "dotted header", source: "[a.\"b.c\"]\nd = \"value\"", path: []string{"a", "b.c", "d"}, want: "value"},
+		{name: "dotted key in table", source: "[a]\nb.c = \"value\"", path: []string{"a", "b", "c"}, want: "value"},
+		{name: "inline table", source: `a = { b.c = "value", d = 1 }`, path: []string{"a", "b", "c"}, want: "value"},
+		{name: "escapes", source: `a = "\"\\\t\u00e9\U0001F600"`, path: []string{"a"}, want: "\"\\\té😀"},
+		{name: "literal string", source: `a = 'C:\Users\n'`, path: []string{"a"}, want: `C:\Users\n`},
+		{
+			name:   "multi-line basic string",
+			source: "a = \"\"\"\nfirst \"quoted\"\nsecond\\n\"\"\"",
+			path:   []string{"a"},
+			want:   "first \"quoted\"\nsecond\n",
+		},
+		{
+			name:   "line ending backslash",
+			source: "a = \"\"\"\nThe quick \\\n\n    brown fox.\"\"\"",
+			path:   []string{"a"},
+			want:   "The quick brown fox.",
+		},
+		{name: "quotes before closing delimiter", source: `a = """x"" """""`, path: []string{"a"}, want: `x"" ""`},
+		{
+			name:   "multi-line literal string",
+			source: "a = '''\nraw \\n\n'text''''",
+			path:   []string{"a"},
+			want:   "raw \\n\n'text'",
+		},
+		{
+			name:   "array of tables",
+			source: "[[a]]\nb = \"first\"\n\n[[a]]\nb = \"second\"\n[a.c]\nd = \"nested\"",
+			path:   []string{"a", "1", "c", "d"},
+			want:   "nested",
+		},
+		{
+			name:   "multi-line array with comments",
+			source: "a = [\n  \"first\", # comment\n  # comment\n  \"second\",\n]",
+			path:   []string{"a", "1"},
+			want:   "second",
+		},
+		{name: "CRLF", source: "a = \"first\"\r\nb = '''\r\nsecond'''\r\n", path: []string{"b"}, want: "second"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := locale.Parse(strings.NewReader(tc.source), locale.TOML)
+			if err != nil {
+				t.Fatalf("failed to parse file: %v", err)
+			}
+			if value, ok := file.Lookup(tc.path...); !ok || value != tc.want {
+				t.Errorf("expected %q, got %q (found %t)", tc.want, value, ok)
+			}
+		})
+	}
+}
+
+func TestFile_WriteTo_TOML(t *testing.T) {
+	source := `# comment before key
+a = """
+multi-line
+value""" # comment after value
+b = 'kept literal' # not translated
+c = [
+  # comment in array
+  'first',
+]
+
+[[d]] # comment after header
+e = { f = "inline" }
+`
+	file, err := locale.Parse(strings.NewReader(source), locale.TOML)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	existing, err := locale.Parse(strings.NewReader(`b = "kept literal"`), locale.TOML)
+	if err != nil {
+		t.Fatalf("failed to parse existing file: %v", err)
+	}
+	if _, err := file.Translate(context.Background(), &upperTranslator{}, i18n.Options{}, existing); err != nil {
+		t.Fatalf("failed to translate file: %v", err)
+	}
+
+	var b strings.Builder
+	if _, err := file.WriteTo(&b); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	want := `# comment before key
+a = "MULTI-LINE\nVALUE" # comment after value
+b = 'kept literal' # not translated
+c = [
+  # comment in array
+  "FIRST",
+]
+
+[[d]] # comment after header
+e = { f = "INLINE" }
+`
+	if b.String() != want {
+		t.Errorf("unexpected file:\n%s", b.String())
+	}
+
+	// changed values are written as basic strings
+	written, err := locale.Parse(strings.NewReader(b.String()), locale.TOML)
+	if err != nil {
+		t.Fatalf("failed to parse written file: %v", err)
+	}
+	if value, _ := written.Lookup("a"); value != "MULTI-LINE\nVALUE" {
+		t.Errorf("unexpected value %q of written file", value)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if format, err := locale.FormatOf("active.en.toml"); err != nil || format != locale.TOML {
+		t.Errorf("expected TOML format, got %v (%v)", format, err)
+	}
+	if _, err := locale.FormatOf("strings.po"); !errors.Is(err, locale.ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+	if _, err := locale.Parse(strings.NewReader(`{"a": "b"} {}`), locale.JSON); err == nil {
+		t.Error("expected error for trailing data")
+	}
+	if _, err := locale.Parse(strings.NewReader("a: [b"), locale.YAML); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+	for _, source := range []string{
+		`a = "unterminated`,
+		`a = "b" "c"`,
+		"a = 1\na = 2",
+		"[a]\n[[a]]",
+		`a = "\x"`,
+		`a = [1, 2`,
+		`[a`,
+	} {
+		if _, err := locale.Parse(strings.NewReader(source), locale.TOML); err == nil {
+			t.Errorf("expected error for invalid TOML %q", source)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package locale
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/internal/mask"
+)
+
+var (
+	// tokenPattern matches interpolation tokens of popular i18n libraries and HTML tags:
+	// {{name}}, {name}, {count, plural, ...} with one level of nesting, printf verbs (the same as
+	// TranslationOptions.Placeholders keeps), $t(key), @:key and <b>.
+	tokenPattern = regexp.MustCompile(
+		`\{\{[^{}]*\}\}` +
+			`|\{[^{}]*(?:\{[^{}]*\}[^{}]*)*\}` +
+			`|` + mask.PrintfVerb +
+			`|\$t\([^)]*\)` +
+			`|@:[\w.]+` +
+			`|<[^<>]+>`,
+	)
+)
+
+// maskTokens replaces interpolation tokens with HTML img elements, so the value can be translated in HTML mode.
+// Returns nil tokens if the value has none.
+func maskTokens(value string) (string, []string) {
+	locs := tokenPattern.FindAllStringIndex(value, -1)
+	if len(locs) == 0 {
+		return value, nil
+	}
+	var (
+		b      strings.Builder
+		tokens = make([]string, 0, len(locs))
+		last   int
+	)
+	for i, loc := range locs {
+		b.WriteString(mask.Escape(value[last:loc[0]]))
+		b.WriteString(mask.Void(i))
+		tokens = append(tokens, value[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(mask.Escape(value[last:]))
+	return b.String(), tokens
+}
+
+// unmaskTokens restores tokens in the translated value. If a token is lost during translation,
+// gobergamot.PlaceholderLostError is returned.
+func unmaskTokens(translated string, tokens []string, source string) (string, error) {
+	value, lost, _ := mask.Unmask(translated, len(tokens), func(n int) (string, error) {
+		return tokens[n], nil
+	}, mask.Unescape)
+	if len(lost) > 0 {
+		return "", &gobergamot.PlaceholderLostError{Placeholder: tokens[lost[0]], Text: source}
+	}
+	return value, nil
+}
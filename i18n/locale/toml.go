@@ -0,0 +1,500 @@
+package locale
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tomlString is a string value of a TOML file. TOML files are written by replacing changed string values
+// in the source, so comments, formatting and other values are kept as is.
+type tomlString struct {
+	node *yaml.Node
+	// original value
+	value string
+	// location of the value in the source
+	begin, end int
+}
+
+// parseTOML parses TOML into a yaml.v3 document node, keeping order of keys.
+// Tables are mapping nodes and arrays (including arrays of tables) are sequence nodes.
+func parseTOML(data []byte) (*yaml.Node, []tomlString, error) {
+	p := &tomlParser{data: data}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if err := p.parse(root); err != nil {
+		return nil, nil, err
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, p.strings, nil
+}
+
+type tomlParser struct {
+	data    []byte
+	pos     int
+	strings []tomlString
+}
+
+func (p *tomlParser) parse(root *yaml.Node) error {
+	table := root
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+		switch p.data[p.pos] {
+		case '\n':
+			p.pos++
+			continue
+		case '#':
+			p.skipComment()
+			continue
+		case '[':
+			var err error
+			if table, err = p.parseHeader(root); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseKeyValue(table); err != nil {
+				return err
+			}
+		}
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+		switch p.data[p.pos] {
+		case '#':
+			p.skipComment()
+		case '\n':
+			p.pos++
+		default:
+			return p.errorf("expected end of line, got %q", p.data[p.pos])
+		}
+	}
+}
+
+// parseHeader parses [table] and [[array of tables]] headers and returns the table.
+func (p *tomlParser) parseHeader(root *yaml.Node) (*yaml.Node, error) {
+	array := bytes.HasPrefix(p.data[p.pos:], []byte("[["))
+	closing := "]"
+	p.pos++
+	if array {
+		closing = "]]"
+		p.pos++
+	}
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(p.data[p.pos:], []byte(closing)) {
+		return nil, p.errorf("expected %s at the end of table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.table(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	key := keys[len(keys)-1]
+	node := tomlChild(parent, key)
+	switch {
+	case node == nil && array:
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		parent.Content = append(parent.Content, tomlKey(key), node)
+	case node == nil:
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		parent.Content = append(parent.Content, tomlKey(key), node)
+		return node, nil
+	case !array && node.Kind == yaml.MappingNode:
+		return node, nil
+	}
+	if !array || node.Kind != yaml.SequenceNode {
+		return nil, p.errorf("key %q is already defined", key)
+	}
+	table := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, table)
+	return table, nil
+}
+
+// table returns the table at the path of keys, creating missing tables.
+// The last table of an array of tables is used.
+func (p *tomlParser) table(table *yaml.Node, keys []string) (*yaml.Node, error) {
+	for _, key := range keys {
+		node := tomlChild(table, key)
+		if node == nil {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			table.Content = append(table.Content, tomlKey(key), node)
+		}
+		if node.Kind == yaml.SequenceNode && len(node.Content) > 0 {
+			node = node.Content[len(node.Content)-1]
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil, p.errorf("key %q is not a table", key)
+		}
+		table = node
+	}
+	return table, nil
+}
+
+func (p *tomlParser) parseKeyValue(table *yaml.Node) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.eof() || p.data[p.pos] != '=' {
+		return p.errorf("expected = after key")
+	}
+	p.pos++
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	if table, err = p.table(table, keys[:len(keys)-1]); err != nil {
+		return err
+	}
+	key := keys[len(keys)-1]
+	if tomlChild(table, key) != nil {
+		return p.errorf("key %q is already defined", key)
+	}
+	table.Content = append(table.Content, tomlKey(key), value)
+	return nil
+}
+
+// parseKey parses bare, quoted and dotted keys.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("expected key")
+		}
+		switch c := p.data[p.pos]; c {
+		case '"', '\'':
+			key, err := p.parseString(c, false)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		default:
+			begin := p.pos
+			for !p.eof() && isBareKeyChar(p.data[p.pos]) {
+				p.pos++
+			}
+			if p.pos == begin {
+				return nil, p.errorf("expected key, got %q", c)
+			}
+			keys = append(keys, string(p.data[begin:p.pos]))
+		}
+		p.skipSpace()
+		if p.eof() || p.data[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *tomlParser) parseValue() (*yaml.Node, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("expected value")
+	}
+	switch c := p.data[p.pos]; c {
+	case '"', '\'':
+		begin := p.pos
+		multiline := bytes.HasPrefix(p.data[p.pos:], []byte{c, c, c})
+		value, err := p.parseString(c, multiline)
+		if err != nil {
+			return nil, err
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+		p.strings = append(p.strings, tomlString{node: node, value: value, begin: begin, end: p.pos})
+		return node, nil
+	case '[':
+		p.pos++
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for {
+			p.skipBlank()
+			if !p.eof() && p.data[p.pos] == ']' {
+				p.pos++
+				return node, nil
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+			p.skipBlank()
+			if p.eof() {
+				return nil, p.errorf("unterminated array")
+			}
+			switch p.data[p.pos] {
+			case ',':
+				p.pos++
+			case ']':
+			default:
+				return nil, p.errorf("expected , or ] in array, got %q", p.data[p.pos])
+			}
+		}
+	case '{':
+		p.pos++
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		p.skipSpace()
+		if !p.eof() && p.data[p.pos] == '}' {
+			p.pos++
+			return node, nil
+		}
+		for {
+			if err := p.parseKeyValue(node); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if p.eof() {
+				return nil, p.errorf("unterminated inline table")
+			}
+			p.pos++
+			switch p.data[p.pos-1] {
+			case ',':
+			case '}':
+				return node, nil
+			default:
+				return nil, p.errorf("expected , or } in inline table, got %q", p.data[p.pos-1])
+			}
+		}
+	default:
+		// numbers, booleans and dates
+		begin := p.pos
+		for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.data[p.pos])) {
+			p.pos++
+		}
+		value := string(p.data[begin:p.pos])
+		// date and time separated by space
+		if len(value) == len("2006-01-02") && value[4] == '-' &&
+			p.pos+1 < len(p.data) && p.data[p.pos] == ' ' && isDigit(p.data[p.pos+1]) {
+			p.pos++
+			for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.data[p.pos])) {
+				p.pos++
+			}
+			value = string(p.data[begin:p.pos])
+		}
+		switch {
+		case value == "":
+			return nil, p.errorf("expected value, got %q", c)
+		case value == "true" || value == "false":
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: value}, nil
+		case len(value) >= len("00:00") && (value[2] == ':' || value[4] == '-'):
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: value}, nil
+		default:
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value}, nil
+		}
+	}
+}
+
+// parseString parses basic (quote is ") or literal (quote is ') string.
+func (p *tomlParser) parseString(quote byte, multiline bool) (string, error) {
+	var b strings.Builder
+	p.pos++
+	if multiline {
+		p.pos += 2
+		// a line break right after the opening delimiter is trimmed
+		if bytes.HasPrefix(p.data[p.pos:], []byte("\r\n")) {
+			p.pos += 2
+		} else if !p.eof() && p.data[p.pos] == '\n' {
+			p.pos++
+		}
+	}
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.data[p.pos]
+		switch {
+		case c == quote && !multiline:
+			p.pos++
+			return b.String(), nil
+		case c == quote:
+			n := 0
+			for p.pos+n < len(p.data) && p.data[p.pos+n] == quote {
+				n++
+			}
+			if n < 3 {
+				b.WriteString(strings.Repeat(string(quote), n))
+				p.pos += n
+				continue
+			}
+			// up to two quotes are allowed right before the closing delimiter
+			b.WriteString(strings.Repeat(string(quote), min(n-3, 2)))
+			p.pos += min(n, 5)
+			return b.String(), nil
+		case c == '\n' && !multiline:
+			return "", p.errorf("unterminated string")
+		case c == '\\' && quote == '"':
+			if err := p.parseEscape(&b, multiline); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder, multiline bool) error {
+	p.pos++
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.data[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.data) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+size]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape %q", p.data[p.pos:p.pos+size])
+		}
+		b.WriteRune(rune(code))
+		p.pos += size
+	case ' ', '\t', '\r', '\n':
+		if !multiline {
+			return p.errorf("invalid escape sequence \\%c", c)
+		}
+		// line ending backslash trims whitespace up to the next non-whitespace character
+		p.pos--
+		for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.data[p.pos])) {
+			p.pos++
+		}
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+// skipSpace skips whitespace within a line.
+func (p *tomlParser) skipSpace() {
+	for !p.eof() {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// skipBlank skips whitespace, line breaks and comments.
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		switch {
+		case p.eof():
+			return
+		case p.data[p.pos] == '\n':
+			p.pos++
+		case p.data[p.pos] == '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	if i := bytes.IndexByte(p.data[p.pos:], '\n'); i >= 0 {
+		p.pos += i
+	} else {
+		p.pos = len(p.data)
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	line := bytes.Count(p.data[:min(p.pos, len(p.data))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func tomlChild(table *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(table.Content); i += 2 {
+		if table.Content[i].Value == key {
+			return table.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func tomlKey(key string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// writeTOML writes the source of the file, replacing changed string values with basic strings.
+func writeTOML(buf *bytes.Buffer, source []byte, values []tomlString) {
+	last := 0
+	for _, s := range values {
+		if s.node.Value == s.value {
+			continue
+		}
+		buf.Write(source[last:s.begin])
+		writeTOMLString(buf, s.node.Value)
+		last = s.end
+	}
+	buf.Write(source[last:])
+}
+
+func writeTOMLString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(buf, `\u%04X`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,455 @@
+// Package po translates gettext PO and POT catalogs.
+package po
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/KSpaceer/gobergamot/i18n"
+)
+
+// FlagFuzzy marks entries which translations need review. Machine translated entries are marked with it.
+const FlagFuzzy = "fuzzy"
+
+var ErrSyntax = errors.New("invalid PO syntax")
+
+// Entry is a message of a catalog.
+type Entry struct {
+	// Flags from "#," comment, e.g. "fuzzy" or "c-format"
+	Flags []string
+	// Context from msgctxt
+	Context string
+	// ID is the original message (msgid)
+	ID string
+	// IDPlural is the original plural message (msgid_plural). It is empty for messages without plural forms.
+	IDPlural string
+	// Str contains translation (msgstr) or translations of plural forms (msgstr[N]).
+	Str []string
+	// Obsolete is set for entries commented out with "#~"
+	Obsolete bool
+
+	// original values to detect modifications
+	origFlags []string
+	origStr   []string
+
+	// index of the line where flags line is or must be inserted
+	flagsLine int
+	// set if flags line exists
+	hasFlagsLine bool
+	// index of the first msgstr line
+	strLine int
+}
+
+// IsHeader reports if the entry is a catalog header.
+func (e *Entry) IsHeader() bool {
+	return e.ID == "" && e.Context == "" && !e.Obsolete
+}
+
+// IsTranslated reports if the entry has a non-empty translation.
+func (e *Entry) IsTranslated() bool {
+	return slices.ContainsFunc(e.Str, func(s string) bool { return s != "" })
+}
+
+// HasFlag reports if the entry has the flag.
+func (e *Entry) HasFlag(flag string) bool {
+	return slices.Contains(e.Flags, flag)
+}
+
+func (e *Entry) modified() bool {
+	return !slices.Equal(e.Flags, e.origFlags) || !slices.Equal(e.Str, e.origStr)
+}
+
+// Catalog is a PO or POT file. It keeps the original text, so only entries which flags or translations
+// are changed are written differently.
+type Catalog struct {
+	blocks []block
+	// line ending of the file
+	eol string
+}
+
+// block is either an entry with its lines or lines between entries.
+type block struct {
+	lines []string
+	entry *Entry
+}
+
+// Parse parses a PO or POT file.
+func Parse(r io.Reader) (*Catalog, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{catalog: &Catalog{eol: "\n"}}
+	text := string(data)
+	if strings.Contains(text, "\r\n") {
+		p.catalog.eol = "\r\n"
+	}
+	if rest, ok := strings.CutPrefix(text, "\ufeff"); ok {
+		// keeping BOM as is
+		p.catalog.blocks = append(p.catalog.blocks, block{lines: []string{"\ufeff"}})
+		text = rest
+	}
+	for i, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if err := p.parseLine(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	p.flush()
+	return p.catalog, nil
+}
+
+// Entries returns entries of the catalog in order of the file. Changes of their flags and translations
+// are written by WriteTo.
+func (c *Catalog) Entries() []*Entry {
+	var entries []*Entry
+	for _, b := range c.blocks {
+		if b.entry != nil {
+			entries = append(entries, b.entry)
+		}
+	}
+	return entries
+}
+
+// Header returns a value of the header field, e.g. "Language" or "Plural-Forms".
+func (c *Catalog) Header(name string) string {
+	for _, entry := range c.Entries() {
+		if !entry.IsHeader() || len(entry.Str) == 0 {
+			continue
+		}
+		for _, line := range strings.Split(entry.Str[0], "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if ok && strings.EqualFold(strings.TrimSpace(key), name) {
+				return strings.TrimSpace(value)
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+var nplurals = regexp.MustCompile(`nplurals\s*=\s*(\d+)`)
+
+// PluralForms returns a number of plural forms from Plural-Forms header or 2 if it is not set.
+func (c *Catalog) PluralForms() int {
+	match := nplurals.FindStringSubmatch(c.Header("Plural-Forms"))
+	if match == nil {
+		return 2
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n < 1 {
+		return 2
+	}
+	return n
+}
+
+// Translate fills entries without translations with machine translations and marks them as fuzzy,
+// so they can be reviewed. Header, obsolete and already translated entries are left as is.
+// Singular form is translated from msgid, other plural forms are translated from msgid_plural.
+// Returns a number of filled entries.
+func (c *Catalog) Translate(ctx context.Context, translator i18n.Translator, opts i18n.Options) (int, error) {
+	var (
+		entries []*Entry
+		texts   []string
+	)
+	for _, entry := range c.Entries() {
+		if entry.IsHeader() || entry.Obsolete || entry.IsTranslated() {
+			continue
+		}
+		entries = append(entries, entry)
+		texts = append(texts, entry.ID, entry.IDPlural)
+	}
+	translated, err := i18n.Translate(ctx, translator, texts, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	forms := c.PluralForms()
+	for i, entry := range entries {
+		singular, plural := translated[2*i], translated[2*i+1]
+		if entry.IDPlural == "" {
+			entry.Str = []string{singular}
+		} else {
+			entry.Str = make([]string, forms)
+			for j := range entry.Str {
+				entry.Str[j] = plural
+			}
+			if forms > 1 {
+				entry.Str[0] = singular
+			}
+		}
+		if !entry.HasFlag(FlagFuzzy) {
+			entry.Flags = append(slices.Clip(entry.Flags), FlagFuzzy)
+		}
+	}
+	return len(entries), nil
+}
+
+// WriteTo writes the catalog. Unmodified entries and lines between entries are written exactly as they were parsed.
+func (c *Catalog) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	for _, b := range c.blocks {
+		if b.entry == nil || !b.entry.modified() {
+			for _, line := range b.lines {
+				sb.WriteString(line)
+			}
+			continue
+		}
+		c.writeEntry(&sb, b)
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// writeEntry writes comments and msgid lines of the modified entry as they were,
+// generating flags and msgstr lines.
+func (c *Catalog) writeEntry(sb *strings.Builder, b block) {
+	entry := b.entry
+	prefix := ""
+	if entry.Obsolete {
+		prefix = "#~ "
+	}
+	flagsLine := func() {
+		if len(entry.Flags) != 0 {
+			sb.WriteString("#, " + strings.Join(entry.Flags, ", ") + c.eol)
+		}
+	}
+	for i, line := range b.lines[:entry.strLine] {
+		if i == entry.flagsLine {
+			flagsLine()
+			if entry.hasFlagsLine {
+				continue
+			}
+		}
+		sb.WriteString(line)
+	}
+	if entry.strLine != 0 && !strings.HasSuffix(b.lines[entry.strLine-1], "\n") {
+		// the entry was at the end of the file without trailing line break
+		sb.WriteString(c.eol)
+	}
+	if entry.flagsLine == entry.strLine {
+		flagsLine()
+	}
+
+	if entry.IDPlural == "" && len(entry.Str) <= 1 {
+		str := ""
+		if len(entry.Str) == 1 {
+			str = entry.Str[0]
+		}
+		c.writeString(sb, prefix, "msgstr", str)
+		return
+	}
+	for i, str := range entry.Str {
+		c.writeString(sb, prefix, "msgstr["+strconv.Itoa(i)+"]", str)
+	}
+}
+
+// writeString writes a keyword with a quoted string. Strings with line breaks are written on multiple lines.
+func (c *Catalog) writeString(sb *strings.Builder, prefix, keyword, s string) {
+	if !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") {
+		sb.WriteString(prefix + keyword + " " + quote(s) + c.eol)
+		return
+	}
+	sb.WriteString(prefix + keyword + ` ""` + c.eol)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			sb.WriteString(prefix + quote(line) + c.eol)
+		}
+	}
+}
+
+type parser struct {
+	catalog *Catalog
+	lines   []string
+	entry   *Entry
+	// string which continuation lines are appended to
+	field *string
+	// set if msgstr of the current entry is parsed
+	seenStr bool
+}
+
+func (p *parser) parseLine(line string) error {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		p.flush()
+		// merging consecutive blank lines
+		if n := len(p.catalog.blocks); n != 0 && p.catalog.blocks[n-1].entry == nil {
+			p.catalog.blocks[n-1].lines = append(p.catalog.blocks[n-1].lines, line)
+		} else {
+			p.catalog.blocks = append(p.catalog.blocks, block{lines: []string{line}})
+		}
+		return nil
+	}
+
+	obsolete := false
+	if rest, ok := strings.CutPrefix(trimmed, "#~"); ok && !strings.HasPrefix(rest, "|") {
+		obsolete = true
+		trimmed = strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(trimmed, "#") {
+		if p.seenStr {
+			p.flush()
+		}
+		p.currentEntry()
+		if strings.HasPrefix(trimmed, "#,") {
+			p.entry.flagsLine = len(p.lines)
+			p.entry.hasFlagsLine = true
+			for _, flag := range strings.Split(trimmed[2:], ",") {
+				if flag = strings.TrimSpace(flag); flag != "" {
+					p.entry.Flags = append(p.entry.Flags, flag)
+				}
+			}
+		} else if strings.HasPrefix(trimmed, "#|") && !p.entry.hasFlagsLine {
+			// flags go before previous msgid
+			p.entry.flagsLine = min(p.entry.flagsLine, len(p.lines))
+		}
+		p.lines = append(p.lines, line)
+		return nil
+	}
+
+	if strings.HasPrefix(trimmed, `"`) {
+		if p.field == nil {
+			return fmt.Errorf("%w: string without keyword", ErrSyntax)
+		}
+		s, err := unquote(trimmed)
+		if err != nil {
+			return err
+		}
+		*p.field += s
+		p.lines = append(p.lines, line)
+		return nil
+	}
+
+	idx := strings.IndexAny(trimmed, " \t")
+	if idx < 0 {
+		return fmt.Errorf("%w: %q", ErrSyntax, trimmed)
+	}
+	keyword := trimmed[:idx]
+	s, err := unquote(strings.TrimSpace(trimmed[idx:]))
+	if err != nil {
+		return err
+	}
+	if (keyword == "msgctxt" || keyword == "msgid") && p.seenStr {
+		p.flush()
+	}
+	entry := p.currentEntry()
+	entry.Obsolete = entry.Obsolete || obsolete
+	if !entry.hasFlagsLine {
+		entry.flagsLine = min(entry.flagsLine, len(p.lines))
+	}
+	switch {
+	case keyword == "msgctxt":
+		entry.Context = s
+		p.field = &entry.Context
+	case keyword == "msgid":
+		entry.ID = s
+		p.field = &entry.ID
+	case keyword == "msgid_plural":
+		entry.IDPlural = s
+		p.field = &entry.IDPlural
+	case keyword == "msgstr" || strings.HasPrefix(keyword, "msgstr["):
+		idx := 0
+		if keyword != "msgstr" {
+			idx, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(keyword, "msgstr["), "]"))
+			if err != nil || idx < 0 || !strings.HasSuffix(keyword, "]") {
+				return fmt.Errorf("%w: invalid keyword %q", ErrSyntax, keyword)
+			}
+		}
+		if !p.seenStr {
+			entry.strLine = len(p.lines)
+			p.seenStr = true
+		}
+		for len(entry.Str) <= idx {
+			entry.Str = append(entry.Str, "")
+		}
+		entry.Str[idx] = s
+		p.field = &entry.Str[idx]
+	default:
+		return fmt.Errorf("%w: unknown keyword %q", ErrSyntax, keyword)
+	}
+	p.lines = append(p.lines, line)
+	return nil
+}
+
+func (p *parser) currentEntry() *Entry {
+	if p.entry == nil {
+		// flags line position is decreased when comments and keywords are parsed
+		p.entry = &Entry{flagsLine: math.MaxInt, strLine: -1}
+	}
+	return p.entry
+}
+
+// flush finishes the current entry.
+func (p *parser) flush() {
+	if len(p.lines) == 0 {
+		return
+	}
+	entry := p.entry
+	if entry.flagsLine > len(p.lines) {
+		entry.flagsLine = len(p.lines)
+	}
+	if entry.strLine < 0 {
+		// entries without msgstr get it after all lines
+		entry.strLine = len(p.lines)
+	}
+	entry.origFlags = slices.Clone(entry.Flags)
+	entry.origStr = slices.Clone(entry.Str)
+	p.catalog.blocks = append(p.catalog.blocks, block{lines: p.lines, entry: entry})
+	p.lines, p.entry, p.field, p.seenStr = nil, nil, nil, false
+}
+
+// unquote unquotes a C-like string literal.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("%w: invalid string %s", ErrSyntax, s)
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		default:
+			// quotes, backslashes and unknown escapes
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+// quote quotes a string as a C-like string literal.
+func quote(s string) string {
+	return `"` + quoteReplacer.Replace(s) + `"`
+}
@@ -0,0 +1,148 @@
+package po_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/i18n"
+	"github.com/KSpaceer/gobergamot/i18n/po"
+)
+
+// upperTranslator "translates" texts by converting them to upper case.
+type upperTranslator struct{}
+
+func (upperTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		outputs[i] = strings.ToUpper(requests[i].Text)
+	}
+	return outputs, nil
+}
+
+const catalog = `# Translation of the app.
+msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+#: main.go:10
+msgid  "Hello"
+msgstr "Привет"
+
+#. greeting with a name
+#: main.go:12
+#, c-format
+msgctxt "greeting"
+msgid "Hello, %s"
+msgstr ""
+
+#: main.go:14
+#| msgid "file"
+msgid "one file"
+msgid_plural "many files"
+msgstr[0] ""
+msgstr[1] ""
+msgstr[2] ""
+
+msgid ""
+"First line\n"
+"Second line"
+msgstr ""
+
+#~ msgid "Old"
+#~ msgstr ""
+`
+
+const want = `# Translation of the app.
+msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+#: main.go:10
+msgid  "Hello"
+msgstr "Привет"
+
+#. greeting with a name
+#: main.go:12
+#, c-format, fuzzy
+msgctxt "greeting"
+msgid "Hello, %s"
+msgstr "HELLO, %S"
+
+#: main.go:14
+#, fuzzy
+#| msgid "file"
+msgid "one file"
+msgid_plural "many files"
+msgstr[0] "ONE FILE"
+msgstr[1] "MANY FILES"
+msgstr[2] "MANY FILES"
+
+#, fuzzy
+msgid ""
+"First line\n"
+"Second line"
+msgstr ""
+"FIRST LINE\n"
+"SECOND LINE"
+
+#~ msgid "Old"
+#~ msgstr ""
+`
+
+func TestCatalog_Translate(t *testing.T) {
+	c, err := po.Parse(strings.NewReader(catalog))
+	if err != nil {
+		t.Fatalf("failed to parse catalog: %v", err)
+	}
+	if lang := c.Header("Language"); lang != "ru" {
+		t.Errorf("expected language ru, got %q", lang)
+	}
+	if forms := c.PluralForms(); forms != 3 {
+		t.Errorf("expected 3 plural forms, got %d", forms)
+	}
+
+	var unmodified strings.Builder
+	if _, err := c.WriteTo(&unmodified); err != nil {
+		t.Fatalf("failed to write catalog: %v", err)
+	}
+	if unmodified.String() != catalog {
+		t.Errorf("unmodified catalog differs from the original:\n%s", unmodified.String())
+	}
+
+	n, err := c.Translate(context.Background(), upperTranslator{}, i18n.Options{})
+	if err != nil {
+		t.Fatalf("failed to translate catalog: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 translated entries, got %d", n)
+	}
+
+	var output strings.Builder
+	if _, err := c.WriteTo(&output); err != nil {
+		t.Fatalf("failed to write catalog: %v", err)
+	}
+	if output.String() != want {
+		t.Errorf("unexpected catalog:\n%s", output.String())
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, text := range []string{
+		"msgid \"unterminated\n",
+		"\"no keyword\"\n",
+		"msgfoo \"\"\n",
+		"msgstr[x] \"\"\n",
+	} {
+		if _, err := po.Parse(strings.NewReader(text)); !errors.Is(err, po.ErrSyntax) {
+			t.Errorf("%q: expected ErrSyntax, got %v", text, err)
+		}
+	}
+}
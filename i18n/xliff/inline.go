@@ -0,0 +1,74 @@
+package xliff
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/KSpaceer/gobergamot/internal/mask"
+)
+
+// inlineTag is an inline tag of the source.
+type inlineTag struct {
+	// open is the start tag of the paired tag or the whole tag otherwise
+	open string
+	// close is the end tag of the paired tag
+	close  string
+	paired bool
+}
+
+func attributePattern(name string) *regexp.Regexp {
+	return regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+}
+
+// restoreTags converts the translated HTML back to XLIFF inline content. Tags lost during translation
+// are appended to the end, so the target has the same inline tags as the source.
+func restoreTags(translated string, tags []inlineTag) string {
+	var (
+		b    strings.Builder
+		used = make([]bool, len(tags))
+		// indices of open paired tags, -1 for unknown spans
+		open []int
+		last int
+	)
+	for _, el := range mask.Find(translated) {
+		b.WriteString(mask.Escape(mask.Unescape(translated[last:el.Begin])))
+		last = el.End
+
+		if el.Closing {
+			if el.Paired && len(open) > 0 {
+				idx := open[len(open)-1]
+				open = open[:len(open)-1]
+				if idx >= 0 {
+					b.WriteString(tags[idx].close)
+				}
+			}
+			continue
+		}
+
+		idx := -1
+		if n := el.Index; n >= 0 && n < len(tags) && !used[n] && tags[n].paired == el.Paired {
+			idx = n
+		}
+		if idx >= 0 {
+			used[idx] = true
+			b.WriteString(tags[idx].open)
+		}
+		if el.Paired {
+			open = append(open, idx)
+		}
+	}
+	b.WriteString(mask.Escape(mask.Unescape(translated[last:])))
+
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] >= 0 {
+			b.WriteString(tags[open[i]].close)
+		}
+	}
+	for i, tag := range tags {
+		if !used[i] {
+			b.WriteString(tag.open)
+			b.WriteString(tag.close)
+		}
+	}
+	return b.String()
+}
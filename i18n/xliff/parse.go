@@ -0,0 +1,256 @@
+package xliff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/KSpaceer/gobergamot/internal/mask"
+)
+
+const namespacePrefix = "urn:oasis:names:tc:xliff:document:"
+
+// parser finds segments of the document. Offsets of XML tokens are used to keep the original bytes.
+type parser struct {
+	doc *Document
+	dec *xml.Decoder
+
+	// names of open elements
+	path []string
+	// translate attribute values of open elements
+	translate []bool
+
+	from, to string
+	cur      *segment
+}
+
+func (d *Document) parse() error {
+	p := parser{
+		doc: d,
+		dec: xml.NewDecoder(bytes.NewReader(d.data)),
+	}
+	if err := p.parse(); err != nil {
+		return fmt.Errorf("failed to parse XLIFF: %w", err)
+	}
+	return nil
+}
+
+func (p *parser) next() (tok xml.Token, start, end int, err error) {
+	start = int(p.dec.InputOffset())
+	tok, err = p.dec.Token()
+	end = int(p.dec.InputOffset())
+	return tok, start, end, err
+}
+
+func (p *parser) parse() error {
+	for {
+		tok, start, end, err := p.next()
+		if errors.Is(err, io.EOF) {
+			if p.doc.version == "" {
+				return ErrNotXLIFF
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if err := p.startElement(tok, start, end); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			p.endElement(tok)
+		}
+	}
+}
+
+func (p *parser) startElement(el xml.StartElement, start, end int) error {
+	translate := len(p.translate) == 0 || p.translate[len(p.translate)-1]
+	switch attr(el, "translate") {
+	case "no":
+		translate = false
+	case "yes":
+		translate = true
+	}
+
+	name := el.Name.Local
+	if el.Name.Space != "" && !strings.HasPrefix(el.Name.Space, namespacePrefix) {
+		// elements of extensions and modules
+		name = ""
+	}
+	var parent string
+	if len(p.path) > 0 {
+		parent = p.path[len(p.path)-1]
+	}
+
+	switch {
+	case len(p.path) == 0:
+		if name != "xliff" {
+			return ErrNotXLIFF
+		}
+		version := attr(el, "version")
+		switch {
+		case version == "1.2":
+		case strings.HasPrefix(version, "2."):
+			p.from, p.to = attr(el, "srcLang"), attr(el, "trgLang")
+		default:
+			return fmt.Errorf("%w: %q", ErrUnsupportedVersion, version)
+		}
+		p.doc.version = version
+	case name == "file" && p.doc.version == "1.2":
+		p.from, p.to = attr(el, "source-language"), attr(el, "target-language")
+	case name == "trans-unit" && p.doc.version == "1.2", name == "segment" && p.doc.version != "1.2":
+		p.cur = &segment{
+			from:            p.from,
+			to:              p.to,
+			segmentStart:    start,
+			segmentStartEnd: end,
+		}
+		if !translate {
+			// the segment is skipped, but its elements must be consumed anyway
+			p.cur = nil
+		}
+	case name == "source" && p.cur != nil && (parent == "trans-unit" || parent == "segment"):
+		return p.readSource(start)
+	case name == "target" && p.cur != nil && (parent == "trans-unit" || parent == "segment"):
+		return p.readTarget(start, end)
+	}
+
+	p.path = append(p.path, name)
+	p.translate = append(p.translate, translate)
+	return nil
+}
+
+func (p *parser) endElement(el xml.EndElement) {
+	name := p.path[len(p.path)-1]
+	p.path = p.path[:len(p.path)-1]
+	p.translate = p.translate[:len(p.translate)-1]
+
+	if p.cur != nil && (name == "trans-unit" || name == "segment") {
+		if p.cur.sourceEnd > 0 {
+			p.doc.segments = append(p.doc.segments, p.cur)
+		}
+		p.cur = nil
+	}
+}
+
+// readSource reads inline content of the source, replacing inline tags with HTML elements:
+// tags with translatable content (<g>, <pc>, <mrk>) become <span>, other tags become <img>.
+func (p *parser) readSource(sourceStart int) error {
+	var (
+		html strings.Builder
+		// indices of open paired tags
+		open []int
+	)
+	seg := p.cur
+	for {
+		tok, start, end, err := p.next()
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.CharData:
+			if strings.TrimSpace(string(tok)) != "" {
+				seg.hasText = true
+			}
+			html.WriteString(mask.Escape(string(tok)))
+		case xml.StartElement:
+			idx := len(seg.tags)
+			if isPaired(tok) {
+				seg.tags = append(seg.tags, inlineTag{open: string(p.doc.data[start:end]), paired: true})
+				open = append(open, idx)
+				html.WriteString(mask.Open(idx))
+				continue
+			}
+			// content of other tags is not translated, so they are kept as a whole
+			if err := p.dec.Skip(); err != nil {
+				return err
+			}
+			end = int(p.dec.InputOffset())
+			seg.tags = append(seg.tags, inlineTag{open: string(p.doc.data[start:end])})
+			html.WriteString(mask.Void(idx))
+		case xml.EndElement:
+			if len(open) == 0 {
+				seg.html = html.String()
+				seg.sourceEnd = end
+				seg.indent = indentation(p.doc.data, sourceStart)
+				return nil
+			}
+			idx := open[len(open)-1]
+			open = open[:len(open)-1]
+			// end of self-closing tag has no bytes
+			seg.tags[idx].close = string(p.doc.data[start:end])
+			html.WriteString(mask.Close)
+		}
+	}
+}
+
+// readTarget reads the target to find out if it has any content.
+func (p *parser) readTarget(targetStart, startTagEnd int) error {
+	seg := p.cur
+	seg.hasTarget = true
+	seg.targetStart = targetStart
+	seg.targetStartTag = string(p.doc.data[targetStart:startTagEnd])
+	for depth := 1; depth > 0; {
+		tok, _, end, err := p.next()
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.CharData:
+			if strings.TrimSpace(string(tok)) != "" {
+				seg.targetHasContent = true
+			}
+		case xml.StartElement:
+			seg.targetHasContent = true
+			depth++
+		case xml.EndElement:
+			depth--
+			seg.targetEnd = end
+		}
+	}
+	return nil
+}
+
+func isPaired(el xml.StartElement) bool {
+	switch el.Name.Local {
+	case "g", "pc":
+		return true
+	case "mrk":
+		return attr(el, "mtype") != "protected" && attr(el, "translate") != "no"
+	default:
+		return false
+	}
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name && a.Name.Space == "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// indentation returns the line break and whitespace before the offset if the offset is at the beginning of a line.
+func indentation(data []byte, offset int) string {
+	i := offset
+	for i > 0 && (data[i-1] == ' ' || data[i-1] == '\t') {
+		i--
+	}
+	if i == 0 || data[i-1] != '\n' {
+		return ""
+	}
+	i--
+	if i > 0 && data[i-1] == '\r' {
+		i--
+	}
+	return string(data[i:offset])
+}
@@ -0,0 +1,198 @@
+// Package xliff pre-translates XLIFF 1.2 and 2.x documents.
+package xliff
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/KSpaceer/gobergamot/i18n"
+)
+
+// State values set for machine translated segments.
+const (
+	// StateNeedsReview is set as state attribute of XLIFF 1.2 targets.
+	StateNeedsReview = "needs-review-translation"
+	// State is set as state attribute of XLIFF 2.x segments along with SubState.
+	State = "translated"
+	// SubState is set as subState attribute of XLIFF 2.x segments.
+	SubState = "gobergamot:needs-review"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported XLIFF version")
+	ErrNotXLIFF           = errors.New("document is not XLIFF")
+)
+
+// Document is an XLIFF document. It keeps the original bytes, so writing the document changes
+// only translated segments.
+type Document struct {
+	data     []byte
+	version  string
+	segments []*segment
+}
+
+// segment is a source of translation unit (XLIFF 1.2) or segment (XLIFF 2.x).
+type segment struct {
+	from, to string
+
+	// source content in HTML with inline tags replaced by span and img elements
+	html string
+	tags []inlineTag
+	// set if source has any text to translate
+	hasText bool
+	// offset after </source>
+	sourceEnd int
+	// indentation of <source> element
+	indent string
+
+	// target element range, if it exists
+	hasTarget              bool
+	targetStart, targetEnd int
+	targetStartTag         string
+	targetHasContent       bool
+
+	// XLIFF 2.x segment start tag range
+	segmentStart, segmentStartEnd int
+
+	translation string
+}
+
+// Parse parses an XLIFF 1.2 or 2.x document.
+func Parse(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{data: data}
+	if err := d.parse(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Version returns XLIFF version of the document, e.g. "1.2".
+func (d *Document) Version() string {
+	return d.version
+}
+
+// Translate translates sources of segments without targets or with empty targets. Inline tags are kept
+// by translating sources in HTML mode. Translated XLIFF 1.2 targets get "needs-review-translation" state,
+// translated XLIFF 2.x segments get "translated" state with "gobergamot:needs-review" subState.
+// Segments of units with translate="no" are skipped.
+//
+// If opts.From and opts.To are empty, languages of the document are used.
+// Returns a number of translated segments.
+func (d *Document) Translate(ctx context.Context, translator i18n.Translator, opts i18n.Options) (int, error) {
+	opts.TranslationOptions.HTML = true
+
+	// translating segments of every language pair separately, so they can be routed by gobergamot.Registry
+	groups := make(map[[2]string][]*segment)
+	var pairs [][2]string
+	for _, s := range d.segments {
+		if !s.hasText || s.targetHasContent {
+			continue
+		}
+		pair := [2]string{s.from, s.to}
+		if _, ok := groups[pair]; !ok {
+			pairs = append(pairs, pair)
+		}
+		groups[pair] = append(groups[pair], s)
+	}
+
+	var translatedSegments int
+	for _, pair := range pairs {
+		pairOpts := opts
+		if pairOpts.From == "" && pairOpts.To == "" {
+			pairOpts.From, pairOpts.To = pair[0], pair[1]
+		}
+		segments := groups[pair]
+		texts := make([]string, len(segments))
+		for i, s := range segments {
+			texts[i] = s.html
+		}
+		translated, err := i18n.Translate(ctx, translator, texts, pairOpts)
+		if err != nil {
+			return translatedSegments, err
+		}
+		for i, s := range segments {
+			s.translation = restoreTags(translated[i], s.tags)
+		}
+		translatedSegments += len(segments)
+	}
+	return translatedSegments, nil
+}
+
+// WriteTo writes the document with translated targets.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, s := range d.segments {
+		if s.translation == "" {
+			continue
+		}
+		var targetStartTag string
+		switch {
+		case s.hasTarget && d.version == "1.2":
+			targetStartTag = setAttribute(s.targetStartTag, "state", StateNeedsReview)
+		case s.hasTarget:
+			targetStartTag = setAttribute(s.targetStartTag, "", "")
+		case d.version == "1.2":
+			targetStartTag = `<target state="` + StateNeedsReview + `">`
+		default:
+			targetStartTag = "<target>"
+		}
+		target := targetStartTag + s.translation + "</target>"
+		if s.hasTarget {
+			edits = append(edits, edit{start: s.targetStart, end: s.targetEnd, text: target})
+		} else {
+			edits = append(edits, edit{start: s.sourceEnd, end: s.sourceEnd, text: s.indent + target})
+		}
+		if d.version != "1.2" {
+			segmentStartTag := string(d.data[s.segmentStart:s.segmentStartEnd])
+			segmentStartTag = setAttribute(segmentStartTag, "state", State)
+			segmentStartTag = setAttribute(segmentStartTag, "subState", SubState)
+			edits = append(edits, edit{start: s.segmentStart, end: s.segmentStartEnd, text: segmentStartTag})
+		}
+	}
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var (
+		buf  bytes.Buffer
+		prev int
+	)
+	for _, e := range edits {
+		buf.Write(d.data[prev:e.start])
+		buf.WriteString(e.text)
+		prev = e.end
+	}
+	buf.Write(d.data[prev:])
+	return buf.WriteTo(w)
+}
+
+// setAttribute sets the attribute of the start tag, replacing its value if it exists.
+// Self-closing start tag is converted into the regular one. If name is empty, only the conversion is made.
+func setAttribute(startTag, name, value string) string {
+	tag := strings.TrimSuffix(strings.TrimSuffix(startTag, ">"), "/")
+	tag = strings.TrimRight(tag, " \t\r\n")
+	if name == "" {
+		return tag + ">"
+	}
+	if loc := attributePattern(name).FindStringSubmatchIndex(tag); loc != nil {
+		return tag[:loc[2]] + `"` + escapeAttribute(value) + `"` + tag[loc[3]:] + ">"
+	}
+	return fmt.Sprintf(`%s %s="%s">`, tag, name, escapeAttribute(value))
+}
+
+func escapeAttribute(value string) string {
+	return strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `"`, `&quot;`).Replace(value)
+}
@@ -0,0 +1,262 @@
+package xliff_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/i18n"
+	"github.com/KSpaceer/gobergamot/i18n/xliff"
+)
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// upperTranslator "translates" texts by converting them to upper case, keeping HTML tags as is,
+// and records language pairs of requests.
+type upperTranslator struct {
+	pairs []string
+}
+
+func (t *upperTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		if !requests[i].Options.HTML {
+			return nil, errors.New("HTML option is not set")
+		}
+		t.pairs = append(t.pairs, requests[i].From+"-"+requests[i].To)
+		var (
+			text = requests[i].Text
+			b    strings.Builder
+			last int
+		)
+		for _, loc := range htmlTag.FindAllStringIndex(text, -1) {
+			b.WriteString(strings.ToUpper(text[last:loc[0]]))
+			b.WriteString(text[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		b.WriteString(strings.ToUpper(text[last:]))
+		outputs[i] = b.String()
+	}
+	return outputs, nil
+}
+
+func TestDocument_Translate(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+		pairs    []string
+	}{
+		{
+			name: "XLIFF 1.2",
+			document: `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <!-- exported by the CAT tool -->
+  <file original="app.html" source-language="en" target-language="ru" datatype="html">
+    <body>
+      <trans-unit id="1">
+        <source>Click <g id="1" ctype="bold">here</g> to continue<x id="2"/></source>
+      </trans-unit>
+      <trans-unit id="2">
+        <source>Tom &amp; Jerry <ph id="1">&lt;br/&gt;</ph></source>
+        <target/>
+      </trans-unit>
+      <trans-unit id="3">
+        <source>Done</source>
+        <target state="translated">Готово</target>
+      </trans-unit>
+      <trans-unit id="4" translate="no">
+        <source>gobergamot</source>
+      </trans-unit>
+      <trans-unit id="5">
+        <source>Save</source>
+        <target xml:lang="ru" state="new"></target>
+        <note>button</note>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <!-- exported by the CAT tool -->
+  <file original="app.html" source-language="en" target-language="ru" datatype="html">
+    <body>
+      <trans-unit id="1">
+        <source>Click <g id="1" ctype="bold">here</g> to continue<x id="2"/></source>
+        <target state="needs-review-translation">CLICK <g id="1" ctype="bold">HERE</g> TO CONTINUE<x id="2"/></target>
+      </trans-unit>
+      <trans-unit id="2">
+        <source>Tom &amp; Jerry <ph id="1">&lt;br/&gt;</ph></source>
+        <target state="needs-review-translation">TOM &amp; JERRY <ph id="1">&lt;br/&gt;</ph></target>
+      </trans-unit>
+      <trans-unit id="3">
+        <source>Done</source>
+        <target state="translated">Готово</target>
+      </trans-unit>
+      <trans-unit id="4" translate="no">
+        <source>gobergamot</source>
+      </trans-unit>
+      <trans-unit id="5">
+        <source>Save</source>
+        <target xml:lang="ru" state="needs-review-translation">SAVE</target>
+        <note>button</note>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`,
+			pairs: []string{"en-ru", "en-ru", "en-ru"},
+		},
+		{
+			name: "XLIFF 2.0",
+			document: `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="de">
+	<file id="f1">
+		<unit id="u1">
+			<segment id="s1">
+				<source>Open <pc id="1">the file</pc><ph id="2"/>.</source>
+			</segment>
+			<ignorable>
+				<source> </source>
+			</ignorable>
+			<segment id="s2" state="initial">
+				<source>Close it</source>
+				<target></target>
+			</segment>
+		</unit>
+		<unit id="u2" translate="no">
+			<segment>
+				<source>Ctrl+S</source>
+			</segment>
+		</unit>
+	</file>
+</xliff>`,
+			want: `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="de">
+	<file id="f1">
+		<unit id="u1">
+			<segment id="s1" state="translated" subState="gobergamot:needs-review">
+				<source>Open <pc id="1">the file</pc><ph id="2"/>.</source>
+				<target>OPEN <pc id="1">THE FILE</pc><ph id="2"/>.</target>
+			</segment>
+			<ignorable>
+				<source> </source>
+			</ignorable>
+			<segment id="s2" state="translated" subState="gobergamot:needs-review">
+				<source>Close it</source>
+				<target>CLOSE IT</target>
+			</segment>
+		</unit>
+		<unit id="u2" translate="no">
+			<segment>
+				<source>Ctrl+S</source>
+			</segment>
+		</unit>
+	</file>
+</xliff>`,
+			pairs: []string{"en-de", "en-de"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := xliff.Parse(strings.NewReader(tc.document))
+			if err != nil {
+				t.Fatalf("failed to parse document: %v", err)
+			}
+			translator := &upperTranslator{}
+			n, err := doc.Translate(context.Background(), translator, i18n.Options{})
+			if err != nil {
+				t.Fatalf("failed to translate document: %v", err)
+			}
+			if n != len(tc.pairs) {
+				t.Errorf("expected %d translated segments, got %d", len(tc.pairs), n)
+			}
+			if fmt.Sprint(translator.pairs) != fmt.Sprint(tc.pairs) {
+				t.Errorf("expected language pairs %v, got %v", tc.pairs, translator.pairs)
+			}
+
+			var b strings.Builder
+			if _, err := doc.WriteTo(&b); err != nil {
+				t.Fatalf("failed to write document: %v", err)
+			}
+			if b.String() != tc.want {
+				t.Errorf("unexpected document:\n%s", b.String())
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		err      error
+	}{
+		{
+			name:     "not XLIFF",
+			document: `<html><body>Hello</body></html>`,
+			err:      xliff.ErrNotXLIFF,
+		},
+		{
+			name:     "empty",
+			document: ``,
+			err:      xliff.ErrNotXLIFF,
+		},
+		{
+			name:     "unsupported version",
+			document: `<xliff version="1.1"></xliff>`,
+			err:      xliff.ErrUnsupportedVersion,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := xliff.Parse(strings.NewReader(tc.document)); !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+
+	if _, err := xliff.Parse(strings.NewReader(`<xliff version="1.2"><file>`)); err == nil {
+		t.Error("expected error for malformed document")
+	}
+}
+
+func TestRestoreLostTags(t *testing.T) {
+	doc, err := xliff.Parse(strings.NewReader(
+		`<xliff version="1.2"><file><body><trans-unit id="1"><source>a <g id="1">b</g> <x id="2"/></source></trans-unit></body></file></xliff>`,
+	))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	if _, err := doc.Translate(context.Background(), lossyTranslator{}, i18n.Options{}); err != nil {
+		t.Fatalf("failed to translate document: %v", err)
+	}
+	var b strings.Builder
+	if _, err := doc.WriteTo(&b); err != nil {
+		t.Fatalf("failed to write document: %v", err)
+	}
+	want := `<target state="needs-review-translation">A &lt;B&gt; &amp; C<g id="1"></g><x id="2"/></target>`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("expected document to contain %s, got:\n%s", want, b.String())
+	}
+}
+
+// lossyTranslator drops all HTML tags.
+type lossyTranslator struct{}
+
+func (lossyTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		outputs[i] = "A &lt;B&gt; &amp; C"
+	}
+	return outputs, nil
+}
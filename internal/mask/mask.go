@@ -0,0 +1,125 @@
+// Package mask replaces fragments of texts, which must be kept as is in translation (placeholders, inline tags,
+// glossary terms), with HTML elements, so the texts can be translated by Bergamot in HTML mode
+// and the fragments can be put back into the translations.
+//
+// Fragment without translatable content is replaced with <img id="pN">, fragment wrapping translatable
+// content is replaced with <span id="pN"> and </span>, where N is an index of the fragment.
+package mask
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Close is the end of the element masking a fragment with translatable content.
+const Close = "</span>"
+
+// PrintfVerb is a regular expression matching verbs of Go, C and Python formatting
+// like %s, %5.2f, %[1]d, %1$s and %(name)s, which are kept as is in translation.
+const PrintfVerb = `%(?:\d+\$|\([^)]*\)|\[\d+\])?[-+#0]*(?:\d+|\*)?(?:\.(?:\d+|\*)?)?(?:\[\d+\])?[bcdeEfFgGiopqsStTuUvxX%]`
+
+var (
+	elementPattern = regexp.MustCompile(`<(/?)(span|img)\b([^>]*)>`)
+	idPattern      = regexp.MustCompile(`\bid="p(\d+)"`)
+
+	textEscaper = strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`)
+)
+
+// Escape escapes the text, so it can be a part of HTML.
+func Escape(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// Unescape converts translated HTML text back to plain text.
+func Unescape(text string) string {
+	return html.UnescapeString(text)
+}
+
+// Void returns the element masking n-th fragment without translatable content.
+func Void(n int) string {
+	return fmt.Sprintf(`<img id="p%d">`, n)
+}
+
+// Open returns the start of the element masking n-th fragment with translatable content.
+func Open(n int) string {
+	return fmt.Sprintf(`<span id="p%d">`, n)
+}
+
+// Element is an element found in the translation.
+type Element struct {
+	// Begin and End are the location of the element in the translation
+	Begin, End int
+	// Index of the masked fragment or -1 if the element has no valid id
+	Index int
+	// Paired is set for span elements
+	Paired bool
+	// Closing is set for end tags
+	Closing bool
+}
+
+// Find returns img and span elements of the translation.
+func Find(translated string) []Element {
+	locs := elementPattern.FindAllStringSubmatchIndex(translated, -1)
+	elements := make([]Element, 0, len(locs))
+	for _, loc := range locs {
+		el := Element{
+			Begin:   loc[0],
+			End:     loc[1],
+			Index:   -1,
+			Paired:  translated[loc[4]:loc[5]] == "span",
+			Closing: loc[3] > loc[2],
+		}
+		if m := idPattern.FindStringSubmatch(translated[loc[6]:loc[7]]); m != nil && !el.Closing {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				el.Index = n
+			}
+		}
+		elements = append(elements, el)
+	}
+	return elements
+}
+
+// Unmask replaces img elements of the translation with fragments returned by fragment func and passes the text
+// between them through text func. Elements with indices out of [0, count) and repeated ones are made up
+// by the model, so they are dropped, while elements without index are a part of the text.
+// Returns indices of fragments missing in the translation.
+func Unmask(
+	translated string,
+	count int,
+	fragment func(n int) (string, error),
+	text func(string) string,
+) (string, []int, error) {
+	var (
+		b     strings.Builder
+		found = make([]bool, count)
+		last  int
+	)
+	for _, el := range Find(translated) {
+		if el.Paired || el.Index < 0 {
+			continue
+		}
+		b.WriteString(text(translated[last:el.Begin]))
+		last = el.End
+		if el.Index >= count || found[el.Index] {
+			continue
+		}
+		found[el.Index] = true
+		s, err := fragment(el.Index)
+		if err != nil {
+			return "", nil, err
+		}
+		b.WriteString(s)
+	}
+	b.WriteString(text(translated[last:]))
+
+	var lost []int
+	for i := range found {
+		if !found[i] {
+			lost = append(lost, i)
+		}
+	}
+	return b.String(), lost, nil
+}
@@ -0,0 +1,97 @@
+package mask_test
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot/internal/mask"
+)
+
+func TestFind(t *testing.T) {
+	text := mask.Escape("a < b") + mask.Void(0) + mask.Open(1) + "c" + mask.Close + `<img src="x.png"><span id="p2" class="x">`
+	expected := []mask.Element{
+		{Begin: 8, End: 21, Index: 0},
+		{Begin: 21, End: 35, Index: 1, Paired: true},
+		{Begin: 36, End: 43, Index: -1, Paired: true, Closing: true},
+		{Begin: 43, End: 60, Index: -1},
+		{Begin: 60, End: 84, Index: 2, Paired: true},
+	}
+	if elements := mask.Find(text); !reflect.DeepEqual(elements, expected) {
+		t.Errorf("expected elements %+v, got %+v", expected, elements)
+	}
+}
+
+func TestUnmask(t *testing.T) {
+	fragments := []string{"{name}", "%d"}
+	fragment := func(n int) (string, error) {
+		return fragments[n], nil
+	}
+
+	tests := []struct {
+		name       string
+		translated string
+		expected   string
+		lost       []int
+	}{
+		{
+			name:       "all fragments",
+			translated: `HELLO ` + mask.Void(0) + `, YOU HAVE ` + mask.Void(1) + ` &amp; MORE`,
+			expected:   "HELLO {name}, YOU HAVE %d & MORE",
+		},
+		{
+			name:       "reordered fragments",
+			translated: mask.Void(1) + ` ` + mask.Void(0),
+			expected:   "%d {name}",
+		},
+		{
+			name:       "repeated and made up fragments",
+			translated: mask.Void(0) + mask.Void(0) + mask.Void(5) + mask.Void(1),
+			expected:   "{name}%d",
+		},
+		{
+			name:       "elements without index",
+			translated: `<img src="x.png">` + mask.Void(0) + `<span>A</span>` + mask.Void(1),
+			expected:   `<img src="x.png">{name}<span>A</span>%d`,
+		},
+		{
+			name:       "lost fragment",
+			translated: `HELLO ` + mask.Void(1),
+			expected:   "HELLO %d",
+			lost:       []int{0},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			output, lost, err := mask.Unmask(tc.translated, len(fragments), fragment, mask.Unescape)
+			if err != nil {
+				t.Fatalf("failed to unmask: %v", err)
+			}
+			if output != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, output)
+			}
+			if !reflect.DeepEqual(lost, tc.lost) {
+				t.Errorf("expected lost fragments %v, got %v", tc.lost, lost)
+			}
+		})
+	}
+
+	fragmentErr := errors.New("fragment error")
+	_, _, err := mask.Unmask(mask.Void(0), 1, func(int) (string, error) {
+		return "", fragmentErr
+	}, strings.ToUpper)
+	if !errors.Is(err, fragmentErr) {
+		t.Errorf("expected fragment error, got %v", err)
+	}
+}
+
+func TestPrintfVerb(t *testing.T) {
+	verb := regexp.MustCompile(mask.PrintfVerb)
+	text := "%s %5.2f %-3d %[1]d %1$s %(name)s %% %*d %.*f %q % d 50% off %y"
+	expected := []string{"%s", "%5.2f", "%-3d", "%[1]d", "%1$s", "%(name)s", "%%", "%*d", "%.*f", "%q"}
+	if verbs := verb.FindAllString(text, -1); !reflect.DeepEqual(verbs, expected) {
+		t.Errorf("expected verbs %q, got %q", expected, verbs)
+	}
+}
@@ -2,6 +2,7 @@ package wasm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	embind "github.com/jerbob92/wazero-emscripten-embind"
@@ -11,6 +12,12 @@ import (
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
+// ErrOutOfMemory is raised by the module if it can't allocate memory.
+var ErrOutOfMemory = errors.New("WASM module is out of memory")
+
+// ErrUnimplementedHostFunction is raised by host functions which are imported by the module but must never be called.
+var ErrUnimplementedHostFunction = errors.New("unimplemented host function")
+
 func BuildImports(
 	ctx context.Context,
 	wasmRuntime wazero.Runtime,
@@ -37,8 +44,15 @@ func BuildImports(
 		return fmt.Errorf("embind ExportFunctions %w", err)
 	}
 
+	// Called by C++ new_handler when allocation fails (see patches/bergamot.diff). Panicking unwinds the module,
+	// and wazero wraps the panic value into the returned error, so the failure matches ErrOutOfMemory
+	// instead of being a generic abort trap.
+	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module) {
+		panic(ErrOutOfMemory)
+	}).Export("gobergamot_out_of_memory")
+
 	// Even with -sFILESYSTEM=0 and -sPURE_WASI emscripten imports these syscalls and aborts them in JavaScript.
-	// They should never get called, so they panic/no-op if they do.
+	// They should never get called, so they panic with ErrUnimplementedHostFunction/no-op if they do.
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, commandPtr int32) int32 {
 		// http://pubs.opengroup.org/onlinepubs/000095399/functions/system.html
@@ -49,39 +63,39 @@ func BuildImports(
 	}).Export("system")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module) {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__cxa_rethrow")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, buf, len int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__syscall_getcwd")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, dirfd, path, flags int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__syscall_unlinkat")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, path int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__syscall_rmdir")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, fd, dirp, count int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__syscall_getdents64")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, dirfd, path, buf, bufsize int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__syscall_readlinkat")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, dirfd, path, buf, bufsize int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__syscall_faccessat")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module, dirfd, path, buf, bufsize int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("__syscall_renameat")
 
 	env.NewFunctionBuilder().WithFunc(func(ctx context.Context, fd int32) int32 {
-		panic("unimplemented host func")
+		panic(ErrUnimplementedHostFunction)
 	}).Export("pclose")
 
 	_, err = env.Instantiate(ctx)
@@ -126,7 +140,7 @@ func buildGemmModule(
 		_, err := fn.Call(ctx, api.EncodeI32(inputA), api.EncodeF32(scale), api.EncodeF32(zeroPoint),
 			api.EncodeU32(rowsA), api.EncodeU32(width), api.EncodeI32(output))
 		if err != nil {
-			panic("failed to call fallback function")
+			panic(fmt.Errorf("failed to call fallback function: %w", err))
 		}
 	}).Export("int8_prepare_a")
 
@@ -144,7 +158,7 @@ func buildGemmModule(
 		_, err := fn.Call(ctx, api.EncodeI32(inputB), api.EncodeF32(scale), api.EncodeF32(zeroPoint),
 			api.EncodeU32(width), api.EncodeU32(colsB), api.EncodeI32(output))
 		if err != nil {
-			panic("failed to call fallback function")
+			panic(fmt.Errorf("failed to call fallback function: %w", err))
 		}
 	}).Export("int8_prepare_b")
 
@@ -162,7 +176,7 @@ func buildGemmModule(
 		_, err := fn.Call(ctx, api.EncodeI32(inputBTransposed), api.EncodeF32(scale), api.EncodeF32(zeroPoint),
 			api.EncodeU32(width), api.EncodeU32(colsB), api.EncodeI32(output))
 		if err != nil {
-			panic("failed to call fallback function")
+			panic(fmt.Errorf("failed to call fallback function: %w", err))
 		}
 	}).Export("int8_prepare_b_from_transposed")
 
@@ -178,7 +192,7 @@ func buildGemmModule(
 		_, err := fn.Call(ctx, api.EncodeI32(inputBQuantTransposed), api.EncodeU32(width),
 			api.EncodeU32(colsB), api.EncodeI32(output))
 		if err != nil {
-			panic("failed to call fallback function")
+			panic(fmt.Errorf("failed to call fallback function: %w", err))
 		}
 	}).Export("int8_prepare_b_from_quantized_transposed")
 
@@ -200,7 +214,7 @@ func buildGemmModule(
 			api.EncodeF32(scaleA), api.EncodeF32(zeroPointA), api.EncodeF32(scaleB), api.EncodeU32(width),
 			api.EncodeU32(width), api.EncodeU32(colsB), api.EncodeI32(inputBias), api.EncodeI32(output))
 		if err != nil {
-			panic("failed to call fallback function")
+			panic(fmt.Errorf("failed to call fallback function: %w", err))
 		}
 	}).Export("int8_prepare_bias")
 
@@ -229,7 +243,7 @@ func buildGemmModule(
 			api.EncodeI32(output),
 		)
 		if err != nil {
-			panic("failed to call fallback function")
+			panic(fmt.Errorf("failed to call fallback function: %w", err))
 		}
 	}).Export("int8_multiply_and_add_bias")
 
@@ -250,7 +264,7 @@ func buildGemmModule(
 			api.EncodeI32(output),
 		)
 		if err != nil {
-			panic("failed to call fallback function")
+			panic(fmt.Errorf("failed to call fallback function: %w", err))
 		}
 	}).Export("int8_select_columns_of_b")
 
@@ -0,0 +1,95 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"sync"
+)
+
+// marianLogPattern matches lines written by Marian/Bergamot logger, e.g.
+// "[2024-02-12 10:00:00] [info] Loaded model". Both timestamp and level are optional.
+var marianLogPattern = regexp.MustCompile(`^(?:\[\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?\] )?(?:\[([a-z]+)\] )?(.*)$`)
+
+var marianLogLevels = map[string]slog.Level{
+	"trace":    slog.LevelDebug,
+	"debug":    slog.LevelDebug,
+	"info":     slog.LevelInfo,
+	"warn":     slog.LevelWarn,
+	"warning":  slog.LevelWarn,
+	"error":    slog.LevelError,
+	"critical": slog.LevelError,
+}
+
+// logWriter parses lines written by the module and emits them as log records.
+type logWriter struct {
+	logger *slog.Logger
+	stream string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewLogWriter creates a writer parsing Marian log lines from module output stream (e.g. "stderr")
+// and emitting them as log records. Close emits the unfinished line, if any.
+func NewLogWriter(logger *slog.Logger, stream string) io.WriteCloser {
+	return &logWriter{logger: logger, stream: stream}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		w.log(string(bytes.TrimRight(w.buf[:idx], "\r")))
+		w.buf = w.buf[idx+1:]
+	}
+	// not keeping large underlying array for short unfinished lines
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Close emits the unfinished line written before the module exit or close. The writer remains usable.
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.log(string(bytes.TrimRight(w.buf, "\r")))
+	w.buf = nil
+	return nil
+}
+
+func (w *logWriter) log(line string) {
+	if line == "" {
+		return
+	}
+	level, msg := parseLogLine(line)
+	w.logger.Log(context.Background(), level, msg, slog.String("stream", w.stream))
+}
+
+// parseLogLine extracts level and message from a Marian log line.
+// Lines without known level are logged with info level.
+func parseLogLine(line string) (slog.Level, string) {
+	match := marianLogPattern.FindStringSubmatch(line)
+	if match == nil {
+		return slog.LevelInfo, line
+	}
+	level, ok := marianLogLevels[match[1]]
+	if !ok {
+		if match[1] != "" {
+			// not a level, so returning the bracketed word to the message
+			return slog.LevelInfo, "[" + match[1] + "] " + match[2]
+		}
+		level = slog.LevelInfo
+	}
+	return level, match[2]
+}
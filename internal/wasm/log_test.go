@@ -0,0 +1,78 @@
+package wasm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot/internal/wasm"
+)
+
+func TestLogWriter(t *testing.T) {
+	type record struct {
+		Level  string `json:"level"`
+		Msg    string `json:"msg"`
+		Stream string `json:"stream"`
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	w := wasm.NewLogWriter(logger, "stderr")
+
+	// writing lines in parts to check buffering
+	for _, part := range []string{
+		"[2024-02-12 10:00:00] [info] Loaded model\n[2024-02-12 10:00:01] [warn",
+		"ing] Slow translation\r\n",
+		"[2024-02-12 10:00:02] [error] Failed\n\n",
+		"[marian] Unknown level\n",
+		"plain line\n",
+		"[2024-02-12 10:00:03] [debug] unfinished",
+	} {
+		if _, err := io.WriteString(w, part); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	expected := []record{
+		{Level: "INFO", Msg: "Loaded model", Stream: "stderr"},
+		{Level: "WARN", Msg: "Slow translation", Stream: "stderr"},
+		{Level: "ERROR", Msg: "Failed", Stream: "stderr"},
+		{Level: "INFO", Msg: "[marian] Unknown level", Stream: "stderr"},
+		{Level: "INFO", Msg: "plain line", Stream: "stderr"},
+	}
+	decoder := json.NewDecoder(&buf)
+	for i := range expected {
+		var got record
+		if err := decoder.Decode(&got); err != nil {
+			t.Fatalf("failed to decode record %d: %v", i, err)
+		}
+		if got != expected[i] {
+			t.Errorf("expected record %+v, got %+v", expected[i], got)
+		}
+	}
+	if decoder.More() {
+		t.Errorf("expected unfinished line not to be logged")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	// the decoder has reached the end of the buffer
+	decoder = json.NewDecoder(&buf)
+	var got record
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatalf("failed to decode unfinished line record: %v", err)
+	}
+	if want := (record{Level: "DEBUG", Msg: "unfinished", Stream: "stderr"}); got != want {
+		t.Errorf("expected record %+v, got %+v", want, got)
+	}
+	// nothing is left to log on the second close
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("expected unfinished line to be logged once, got %s", buf.String())
+	}
+}
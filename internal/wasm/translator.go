@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 
 	embind "github.com/jerbob92/wazero-emscripten-embind"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 
 	"github.com/KSpaceer/gobergamot/internal/gen"
 )
@@ -15,6 +17,10 @@ import (
 type CompileConfig struct {
 	// Stderr and Stdout enable redirection of any logs. If left nil they point at os.Stderr and os.Stdout. Turn off by setting them to io.Discard
 	Stderr, Stdout io.Writer
+
+	// Logger receives Bergamot (Marian) log lines from the module output as structured records
+	// with corresponding levels. If it is set, Stderr and Stdout are ignored.
+	Logger *slog.Logger
 }
 
 func BergamotWASM() []byte {
@@ -36,9 +42,20 @@ func CompileBergamot(
 		return nil, fmt.Errorf("BuildImports: %w", err)
 	}
 
+	stderr, stdout := cfg.Stderr, cfg.Stdout
+	if cfg.Logger != nil {
+		stderrLog, stdoutLog := NewLogWriter(cfg.Logger, "stderr"), NewLogWriter(cfg.Logger, "stdout")
+		stderr, stdout = stderrLog, stdoutLog
+		// the module can exit or be closed in the middle of a line, so the rest of it is logged on close
+		ctx = experimental.WithCloseNotifier(ctx, experimental.CloseNotifyFunc(func(context.Context, uint32) {
+			_ = stderrLog.Close()
+			_ = stdoutLog.Close()
+		}))
+	}
+
 	moduleConfig := wazero.NewModuleConfig().
-		WithStderr(cfg.Stderr).
-		WithStdout(cfg.Stdout).
+		WithStderr(stderr).
+		WithStdout(stdout).
 		WithStartFunctions("_initialize")
 
 	bergamotModule, err := wasmRuntime.InstantiateModule(ctx, bergamotCompiledModule, moduleConfig)
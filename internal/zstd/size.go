@@ -0,0 +1,94 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var errInvalidFrame = errors.New("invalid zstd frame")
+
+// ContentSize returns size of decompressed data of the frames from their headers without decompressing them.
+// Returns false if any of the frames doesn't declare its content size.
+func ContentSize(data []byte) (uint64, bool, error) {
+	var size uint64
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return 0, false, io.ErrUnexpectedEOF
+		}
+		magic := binary.LittleEndian.Uint32(data)
+		if magic >= 0x184d2a50 && magic <= 0x184d2a5f {
+			// skippable frame
+			frameSize := uint64(binary.LittleEndian.Uint32(data[4:]))
+			if uint64(len(data)-8) < frameSize {
+				return 0, false, io.ErrUnexpectedEOF
+			}
+			data = data[8+frameSize:]
+			continue
+		}
+		if magic != 0xfd2fb528 {
+			return 0, false, errInvalidFrame
+		}
+
+		// frame header, see readFrameHeader
+		descriptor := data[4]
+		singleSegment := descriptor&(1<<5) != 0
+		fcsFieldSize := 1 << (descriptor >> 6)
+		if fcsFieldSize == 1 && !singleSegment {
+			return 0, false, nil
+		}
+		headerSize := fcsFieldSize
+		if !singleSegment {
+			headerSize++
+		}
+		if dictIDFlag := descriptor & 3; dictIDFlag != 0 {
+			headerSize += 1 << (dictIDFlag - 1)
+		}
+		data = data[5:]
+		if len(data) < headerSize {
+			return 0, false, io.ErrUnexpectedEOF
+		}
+		fb := data[headerSize-fcsFieldSize : headerSize]
+		switch fcsFieldSize {
+		case 1:
+			size += uint64(fb[0])
+		case 2:
+			size += 256 + uint64(binary.LittleEndian.Uint16(fb))
+		case 4:
+			size += uint64(binary.LittleEndian.Uint32(fb))
+		case 8:
+			size += binary.LittleEndian.Uint64(fb)
+		}
+		data = data[headerSize:]
+
+		// skipping blocks of the frame
+		for last := false; !last; {
+			if len(data) < 3 {
+				return 0, false, io.ErrUnexpectedEOF
+			}
+			header := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+			data = data[3:]
+			last = header&1 != 0
+			blockSize := int(header >> 3)
+			switch (header >> 1) & 3 {
+			case 1:
+				// RLE block contains a single byte
+				blockSize = 1
+			case 3:
+				return 0, false, errInvalidFrame
+			}
+			if len(data) < blockSize {
+				return 0, false, io.ErrUnexpectedEOF
+			}
+			data = data[blockSize:]
+		}
+		if descriptor&(1<<2) != 0 {
+			// content checksum
+			if len(data) < 4 {
+				return 0, false, io.ErrUnexpectedEOF
+			}
+			data = data[4:]
+		}
+	}
+	return size, true, nil
+}
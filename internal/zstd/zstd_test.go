@@ -0,0 +1,66 @@
+package zstd_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot/internal/zstd"
+)
+
+// files with frames not declaring content size
+var noContentSize = map[string]bool{
+	"1d43ea62.words-no-content-size.zst": true,
+	"fcf30b99.zero-dictionary-ids.zst":   true,
+}
+
+func TestReader(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		t.Run(name, func(t *testing.T) {
+			compressed, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(zstd.NewReader(bytes.NewReader(compressed)))
+			if err != nil {
+				t.Fatalf("failed to decompress: %v", err)
+			}
+			// files are named by the hash of decompressed data
+			hash := sha256.Sum256(data)
+			if want, _, _ := strings.Cut(name, "."); hex.EncodeToString(hash[:4]) != want {
+				t.Errorf("expected data with hash %s, got %x", want, hash[:4])
+			}
+
+			size, known, err := zstd.ContentSize(compressed)
+			if err != nil {
+				t.Fatalf("failed to get content size: %v", err)
+			}
+			if wantKnown := !noContentSize[name]; known != wantKnown {
+				t.Fatalf("expected content size to be known: %t, got %t", wantKnown, known)
+			}
+			if known && size != uint64(len(data)) {
+				t.Errorf("expected content size %d, got %d", len(data), size)
+			}
+		})
+	}
+}
+
+func TestContentSize_Truncated(t *testing.T) {
+	compressed, err := os.ReadFile("testdata/70fa686d.words-2-frames.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := zstd.ContentSize(compressed[:len(compressed)-10]); err == nil {
+		t.Errorf("expected error for truncated data")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package gobergamot
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile is not supported on this platform, so files are read into memory.
+func mmapFile(*os.File) ([]byte, func() error, error) {
+	return nil, nil, errors.New("mmap is not supported")
+}
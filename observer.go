@@ -0,0 +1,123 @@
+package gobergamot
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives events of Translator, Registry and Pool, e.g. to collect metrics or traces.
+// Methods are called synchronously after the operations, so they must be fast and safe for concurrent use.
+// Embed NopObserver to implement only some of the methods.
+//
+// Ready-to-use Prometheus metrics and spans adapters are available in the observer subpackage.
+type Observer interface {
+	// ObserveNew is called after a Translator is created, including the ones created by Pool workers.
+	ObserveNew(ctx context.Context, event TranslatorEvent)
+	// ObserveTranslate is called after a batch of texts is translated with a single Bergamot call.
+	ObserveTranslate(ctx context.Context, event TranslationEvent)
+	// ObserveDispatch is called after a Pool request is processed by a worker or failed waiting for it.
+	ObserveDispatch(ctx context.Context, event DispatchEvent)
+	// ObserveClose is called after a Translator is closed.
+	ObserveClose(ctx context.Context, event TranslatorEvent)
+}
+
+// TranslatorEvent describes creation or closing of a Translator.
+type TranslatorEvent struct {
+	Start    time.Time
+	Duration time.Duration
+	// ID of the Translator unique within the process. Zero if the Translator failed to start.
+	TranslatorID uint64
+	// Size of the WASM module memory in bytes. Zero if the module is not compiled.
+	MemoryBytes uint64
+	Err         error
+}
+
+// TranslationEvent describes translation of a batch of texts.
+type TranslationEvent struct {
+	Start    time.Time
+	Duration time.Duration
+	// Number of translated texts
+	Texts int
+	// Total size of the texts in bytes
+	InputBytes int
+	// Detailed is true if the results were requested with sentences information
+	Detailed bool
+	// Size of the WASM module memory in bytes after translation
+	MemoryBytes uint64
+	// ID of the Translator unique within the process
+	TranslatorID uint64
+	Err          error
+}
+
+// DispatchEvent describes processing of a Pool request.
+type DispatchEvent struct {
+	Start time.Time
+	// Total time of processing, including QueueWait
+	Duration time.Duration
+	// Time the request was waiting for a free worker in the queue
+	QueueWait time.Duration
+	Priority  Priority
+	// Number of texts in the request
+	Texts int
+	// Number of requests translated together with this one in a single call, including this one.
+	// Zero if the request was not taken by a worker.
+	BatchSize int
+	// ID of the worker processed the request. Valid only if BatchSize is not zero.
+	WorkerID uint
+	Err      error
+}
+
+// NopObserver is an Observer doing nothing. It is used if no Observer is set in Config.
+type NopObserver struct{}
+
+func (NopObserver) ObserveNew(context.Context, TranslatorEvent)        {}
+func (NopObserver) ObserveTranslate(context.Context, TranslationEvent) {}
+func (NopObserver) ObserveDispatch(context.Context, DispatchEvent)     {}
+func (NopObserver) ObserveClose(context.Context, TranslatorEvent)      {}
+
+var _ Observer = NopObserver{}
+
+func observerOrNop(observer Observer) Observer {
+	if observer == nil {
+		return NopObserver{}
+	}
+	return observer
+}
+
+// translatorID returns ID of the translator or zero if it is nil.
+func (t *Translator) translatorID() uint64 {
+	if t == nil {
+		return 0
+	}
+	return t.id
+}
+
+// memoryBytes returns size of the WASM module memory.
+func (t *Translator) memoryBytes() uint64 {
+	if t == nil || t.module == nil || t.module.Memory() == nil {
+		return 0
+	}
+	return uint64(t.module.Memory().Size())
+}
+
+func (t *Translator) observeTranslation(
+	ctx context.Context,
+	start time.Time,
+	requests []TranslationRequest,
+	detailed bool,
+	err error,
+) {
+	event := TranslationEvent{
+		Start:        start,
+		Duration:     time.Since(start),
+		Texts:        len(requests),
+		Detailed:     detailed,
+		MemoryBytes:  t.memoryBytes(),
+		TranslatorID: t.id,
+		Err:          err,
+	}
+	for i := range requests {
+		event.InputBytes += len(requests[i].Text)
+	}
+	t.cfg.Observer.ObserveTranslate(ctx, event)
+}
@@ -0,0 +1,36 @@
+package observer
+
+import (
+	"context"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+// Multi is an Observer passing events to all the observers.
+type Multi []gobergamot.Observer
+
+var _ gobergamot.Observer = Multi(nil)
+
+func (m Multi) ObserveNew(ctx context.Context, event gobergamot.TranslatorEvent) {
+	for _, o := range m {
+		o.ObserveNew(ctx, event)
+	}
+}
+
+func (m Multi) ObserveTranslate(ctx context.Context, event gobergamot.TranslationEvent) {
+	for _, o := range m {
+		o.ObserveTranslate(ctx, event)
+	}
+}
+
+func (m Multi) ObserveDispatch(ctx context.Context, event gobergamot.DispatchEvent) {
+	for _, o := range m {
+		o.ObserveDispatch(ctx, event)
+	}
+}
+
+func (m Multi) ObserveClose(ctx context.Context, event gobergamot.TranslatorEvent) {
+	for _, o := range m {
+		o.ObserveClose(ctx, event)
+	}
+}
@@ -0,0 +1,101 @@
+package observer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/observer"
+)
+
+func TestPrometheus(t *testing.T) {
+	ctx := context.Background()
+	p := observer.NewPrometheus("")
+
+	// memory of every translator is tracked until it is closed
+	p.ObserveNew(ctx, gobergamot.TranslatorEvent{Duration: 2 * time.Second, TranslatorID: 1, MemoryBytes: 1 << 20})
+	p.ObserveNew(ctx, gobergamot.TranslatorEvent{Duration: 2 * time.Second, TranslatorID: 2, MemoryBytes: 1 << 20})
+	p.ObserveNew(ctx, gobergamot.TranslatorEvent{Duration: 2 * time.Second, TranslatorID: 3, MemoryBytes: 1 << 20})
+	p.ObserveNew(ctx, gobergamot.TranslatorEvent{Duration: time.Second, Err: errors.New("failed")})
+	p.ObserveTranslate(ctx, gobergamot.TranslationEvent{
+		Duration:     20 * time.Millisecond,
+		Texts:        2,
+		InputBytes:   10,
+		TranslatorID: 2,
+		MemoryBytes:  3 << 20,
+	})
+	p.ObserveTranslate(ctx, gobergamot.TranslationEvent{Duration: time.Minute, Err: errors.New("failed")})
+	p.ObserveDispatch(ctx, gobergamot.DispatchEvent{QueueWait: time.Millisecond, BatchSize: 3})
+	p.ObserveClose(ctx, gobergamot.TranslatorEvent{TranslatorID: 3, MemoryBytes: 1 << 20})
+
+	var buf bytes.Buffer
+	if _, err := p.WriteTo(&buf); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	output := buf.String()
+
+	for _, line := range []string{
+		"# TYPE gobergamot_translations_total counter",
+		`gobergamot_translators_created_total{result="ok"} 3`,
+		`gobergamot_translators_created_total{result="error"} 1`,
+		`gobergamot_translators_closed_total{result="ok"} 1`,
+		`gobergamot_translations_total{result="ok"} 1`,
+		`gobergamot_translations_total{result="error"} 1`,
+		`gobergamot_translation_duration_seconds_bucket{le="0.025"} 1`,
+		`gobergamot_translation_duration_seconds_bucket{le="30"} 1`,
+		`gobergamot_translation_duration_seconds_bucket{le="+Inf"} 2`,
+		"gobergamot_translation_duration_seconds_sum 60.02",
+		"gobergamot_translation_duration_seconds_count 2",
+		"gobergamot_translated_texts_total 2",
+		"gobergamot_input_bytes_total 10",
+		"gobergamot_translators 2",
+		"gobergamot_wasm_memory_bytes 4.194304e+06",
+		"gobergamot_wasm_memory_max_bytes 3.145728e+06",
+		`gobergamot_pool_requests_total{result="ok"} 1`,
+		`gobergamot_pool_batch_size_bucket{le="2"} 0`,
+		`gobergamot_pool_batch_size_bucket{le="4"} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected line %q in output:\n%s", line, output)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+
+	var spans []observer.Span
+	o := observer.Multi{
+		gobergamot.NopObserver{},
+		observer.NewSpans(func(span observer.Span) {
+			spans = append(spans, span)
+		}),
+	}
+	o.ObserveTranslate(ctx, gobergamot.TranslationEvent{Start: start, Duration: time.Second, Texts: 3})
+	o.ObserveDispatch(ctx, gobergamot.DispatchEvent{Start: start, Duration: time.Second, BatchSize: 2, WorkerID: 1})
+	o.ObserveClose(ctx, gobergamot.TranslatorEvent{Start: start, TranslatorID: 4, MemoryBytes: 1 << 20})
+
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	if spans[0].Name != observer.SpanTranslate || spans[1].Name != observer.SpanDispatch || spans[2].Name != observer.SpanClose {
+		t.Errorf("unexpected span names %q, %q and %q", spans[0].Name, spans[1].Name, spans[2].Name)
+	}
+	if !spans[0].End.Equal(start.Add(time.Second)) {
+		t.Errorf("expected span end %v, got %v", start.Add(time.Second), spans[0].End)
+	}
+	if texts := spans[0].Attributes["gobergamot.texts"]; texts != 3 {
+		t.Errorf("expected 3 texts, got %v", texts)
+	}
+	if workerID := spans[1].Attributes["gobergamot.pool.worker_id"]; workerID != uint(1) {
+		t.Errorf("expected worker ID 1, got %v", workerID)
+	}
+	if id, memory := spans[2].Attributes["gobergamot.translator_id"], spans[2].Attributes["gobergamot.wasm.memory_bytes"]; id != uint64(4) || memory != uint64(1<<20) {
+		t.Errorf("expected translator 4 with 1MiB of memory, got %v with %v bytes", id, memory)
+	}
+}
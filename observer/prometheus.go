@@ -0,0 +1,221 @@
+// Package observer provides gobergamot.Observer implementations exporting metrics in Prometheus text format
+// and spans similar to OpenTelemetry ones.
+package observer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+var (
+	// DurationBuckets are upper bounds (in seconds) of histogram buckets for durations.
+	DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+	// BatchSizeBuckets are upper bounds of histogram buckets for numbers of requests in batches.
+	BatchSizeBuckets = []float64{1, 2, 4, 8, 16, 32, 64, 128}
+)
+
+// Prometheus collects metrics of translators and writes them in Prometheus text exposition format.
+// It implements http.Handler, so it can be registered as a metrics endpoint.
+type Prometheus struct {
+	namespace string
+
+	mu sync.Mutex
+
+	translatorsCreated map[string]uint64
+	translatorsClosed  map[string]uint64
+	newDuration        *histogram
+
+	translations        map[string]uint64
+	translationDuration *histogram
+	translatedTexts     uint64
+	inputBytes          uint64
+	// last observed memory size of open translators by their IDs
+	memoryBytes map[uint64]uint64
+
+	dispatches       map[string]uint64
+	dispatchDuration *histogram
+	queueWait        *histogram
+	batchSize        *histogram
+}
+
+var _ gobergamot.Observer = (*Prometheus)(nil)
+
+// NewPrometheus creates a Prometheus observer. Names of the metrics are prefixed with the namespace,
+// which defaults to "gobergamot".
+func NewPrometheus(namespace string) *Prometheus {
+	if namespace == "" {
+		namespace = "gobergamot"
+	}
+	return &Prometheus{
+		namespace:           namespace,
+		translatorsCreated:  make(map[string]uint64),
+		translatorsClosed:   make(map[string]uint64),
+		newDuration:         newHistogram(DurationBuckets),
+		translations:        make(map[string]uint64),
+		translationDuration: newHistogram(DurationBuckets),
+		memoryBytes:         make(map[uint64]uint64),
+		dispatches:          make(map[string]uint64),
+		dispatchDuration:    newHistogram(DurationBuckets),
+		queueWait:           newHistogram(DurationBuckets),
+		batchSize:           newHistogram(BatchSizeBuckets),
+	}
+}
+
+func (p *Prometheus) ObserveNew(_ context.Context, event gobergamot.TranslatorEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.translatorsCreated[result(event.Err)]++
+	p.newDuration.observe(event.Duration.Seconds())
+	if event.Err == nil && event.MemoryBytes != 0 {
+		p.memoryBytes[event.TranslatorID] = event.MemoryBytes
+	}
+}
+
+func (p *Prometheus) ObserveTranslate(_ context.Context, event gobergamot.TranslationEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.translations[result(event.Err)]++
+	p.translationDuration.observe(event.Duration.Seconds())
+	p.translatedTexts += uint64(event.Texts)
+	p.inputBytes += uint64(event.InputBytes)
+	if event.MemoryBytes != 0 {
+		p.memoryBytes[event.TranslatorID] = event.MemoryBytes
+	}
+}
+
+func (p *Prometheus) ObserveDispatch(_ context.Context, event gobergamot.DispatchEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dispatches[result(event.Err)]++
+	p.dispatchDuration.observe(event.Duration.Seconds())
+	p.queueWait.observe(event.QueueWait.Seconds())
+	if event.BatchSize != 0 {
+		p.batchSize.observe(float64(event.BatchSize))
+	}
+}
+
+func (p *Prometheus) ObserveClose(_ context.Context, event gobergamot.TranslatorEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.translatorsClosed[result(event.Err)]++
+	delete(p.memoryBytes, event.TranslatorID)
+}
+
+// WriteTo writes the metrics in Prometheus text exposition format.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+
+	p.mu.Lock()
+	p.writeCounterVec(&sb, "translators_created_total", "Number of created translators.", p.translatorsCreated)
+	p.writeCounterVec(&sb, "translators_closed_total", "Number of closed translators.", p.translatorsClosed)
+	p.writeHistogram(&sb, "translator_new_duration_seconds", "Time of translator creation.", p.newDuration)
+	p.writeCounterVec(&sb, "translations_total", "Number of Bergamot translation calls.", p.translations)
+	p.writeHistogram(&sb, "translation_duration_seconds", "Time of Bergamot translation calls.", p.translationDuration)
+	p.writeMetric(&sb, "translated_texts_total", "Number of translated texts.", "counter", float64(p.translatedTexts))
+	p.writeMetric(&sb, "input_bytes_total", "Total size of translated texts.", "counter", float64(p.inputBytes))
+	var memorySum, memoryMax uint64
+	for _, bytes := range p.memoryBytes {
+		memorySum += bytes
+		memoryMax = max(memoryMax, bytes)
+	}
+	p.writeMetric(&sb, "translators", "Number of open translators.", "gauge", float64(len(p.memoryBytes)))
+	p.writeMetric(&sb, "wasm_memory_bytes", "Total size of WASM module memory of open translators.", "gauge", float64(memorySum))
+	p.writeMetric(&sb, "wasm_memory_max_bytes", "Size of the largest WASM module memory of open translators.", "gauge", float64(memoryMax))
+	p.writeCounterVec(&sb, "pool_requests_total", "Number of processed pool requests.", p.dispatches)
+	p.writeHistogram(&sb, "pool_request_duration_seconds", "Time of pool requests processing.", p.dispatchDuration)
+	p.writeHistogram(&sb, "pool_queue_wait_seconds", "Time pool requests waited for a free worker.", p.queueWait)
+	p.writeHistogram(&sb, "pool_batch_size", "Number of pool requests translated in a single call.", p.batchSize)
+	p.mu.Unlock()
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+func (p *Prometheus) writeHeader(sb *strings.Builder, name, help, kind string) {
+	fmt.Fprintf(sb, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", p.namespace, name, help, p.namespace, name, kind)
+}
+
+func (p *Prometheus) writeMetric(sb *strings.Builder, name, help, kind string, value float64) {
+	p.writeHeader(sb, name, help, kind)
+	fmt.Fprintf(sb, "%s_%s %s\n", p.namespace, name, formatFloat(value))
+}
+
+func (p *Prometheus) writeCounterVec(sb *strings.Builder, name, help string, values map[string]uint64) {
+	p.writeHeader(sb, name, help, "counter")
+	for _, res := range [...]string{resultOK, resultError} {
+		fmt.Fprintf(sb, "%s_%s{result=%q} %d\n", p.namespace, name, res, values[res])
+	}
+}
+
+func (p *Prometheus) writeHistogram(sb *strings.Builder, name, help string, h *histogram) {
+	p.writeHeader(sb, name, help, "histogram")
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(sb, "%s_%s_bucket{le=%q} %d\n", p.namespace, name, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(sb, "%s_%s_bucket{le=\"+Inf\"} %d\n", p.namespace, name, h.count)
+	fmt.Fprintf(sb, "%s_%s_sum %s\n", p.namespace, name, formatFloat(h.sum))
+	fmt.Fprintf(sb, "%s_%s_count %d\n", p.namespace, name, h.count)
+}
+
+const (
+	resultOK    = "ok"
+	resultError = "error"
+)
+
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultOK
+}
+
+// histogram counts observed values in buckets with given upper bounds.
+type histogram struct {
+	bounds []float64
+	// counts of values in buckets (non-cumulative), the last one is for values above all bounds
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	i := len(h.bounds)
+	for j, bound := range h.bounds {
+		if value <= bound {
+			i = j
+			break
+		}
+	}
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
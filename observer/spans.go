@@ -0,0 +1,113 @@
+package observer
+
+import (
+	"context"
+	"time"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+// Names of the spans produced by Spans observer.
+const (
+	SpanNew       = "gobergamot.New"
+	SpanTranslate = "gobergamot.Translate"
+	SpanDispatch  = "gobergamot.Pool.Dispatch"
+	SpanClose     = "gobergamot.Close"
+)
+
+// Span describes a finished operation similarly to OpenTelemetry span.
+type Span struct {
+	// Context of the operation. Exporter can take the parent span from it.
+	Context    context.Context
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]any
+	// Err is not nil if the operation failed
+	Err error
+}
+
+// Spans is an Observer converting events into spans and passing them to the exporter.
+// Attribute names follow OpenTelemetry naming conventions, so the spans can be easily recorded
+// with OpenTelemetry tracer, e.g.:
+//
+//	observer.NewSpans(func(span observer.Span) {
+//		_, s := tracer.Start(span.Context, span.Name, trace.WithTimestamp(span.Start))
+//		// set attributes and status
+//		s.End(trace.WithTimestamp(span.End))
+//	})
+type Spans struct {
+	export func(Span)
+}
+
+var _ gobergamot.Observer = Spans{}
+
+// NewSpans creates a Spans observer. Export function is called synchronously and must be safe for concurrent use.
+func NewSpans(export func(Span)) Spans {
+	return Spans{export: export}
+}
+
+func (s Spans) ObserveNew(ctx context.Context, event gobergamot.TranslatorEvent) {
+	s.export(Span{
+		Context: ctx,
+		Name:    SpanNew,
+		Start:   event.Start,
+		End:     event.Start.Add(event.Duration),
+		Attributes: map[string]any{
+			"gobergamot.translator_id":     event.TranslatorID,
+			"gobergamot.wasm.memory_bytes": event.MemoryBytes,
+		},
+		Err: event.Err,
+	})
+}
+
+func (s Spans) ObserveTranslate(ctx context.Context, event gobergamot.TranslationEvent) {
+	s.export(Span{
+		Context: ctx,
+		Name:    SpanTranslate,
+		Start:   event.Start,
+		End:     event.Start.Add(event.Duration),
+		Attributes: map[string]any{
+			"gobergamot.texts":             event.Texts,
+			"gobergamot.input_bytes":       event.InputBytes,
+			"gobergamot.detailed":          event.Detailed,
+			"gobergamot.translator_id":     event.TranslatorID,
+			"gobergamot.wasm.memory_bytes": event.MemoryBytes,
+		},
+		Err: event.Err,
+	})
+}
+
+func (s Spans) ObserveDispatch(ctx context.Context, event gobergamot.DispatchEvent) {
+	attributes := map[string]any{
+		"gobergamot.texts":              event.Texts,
+		"gobergamot.pool.priority":      int(event.Priority),
+		"gobergamot.pool.queue_wait_ms": event.QueueWait.Milliseconds(),
+		"gobergamot.pool.batch_size":    event.BatchSize,
+	}
+	if event.BatchSize != 0 {
+		attributes["gobergamot.pool.worker_id"] = event.WorkerID
+	}
+	s.export(Span{
+		Context:    ctx,
+		Name:       SpanDispatch,
+		Start:      event.Start,
+		End:        event.Start.Add(event.Duration),
+		Attributes: attributes,
+		Err:        event.Err,
+	})
+}
+
+func (s Spans) ObserveClose(ctx context.Context, event gobergamot.TranslatorEvent) {
+	s.export(Span{
+		Context: ctx,
+		Name:    SpanClose,
+		Start:   event.Start,
+		End:     event.Start.Add(event.Duration),
+		Attributes: map[string]any{
+			"gobergamot.translator_id":     event.TranslatorID,
+			"gobergamot.wasm.memory_bytes": event.MemoryBytes,
+		},
+		Err: event.Err,
+	})
+}
@@ -0,0 +1,39 @@
+package gobergamot_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+type recordingObserver struct {
+	gobergamot.NopObserver
+	created []gobergamot.TranslatorEvent
+}
+
+func (o *recordingObserver) ObserveNew(_ context.Context, event gobergamot.TranslatorEvent) {
+	o.created = append(o.created, event)
+}
+
+func TestNew_Observer(t *testing.T) {
+	observer := &recordingObserver{}
+	_, err := gobergamot.New(context.Background(), gobergamot.Config{
+		FilesBundle: gobergamot.FilesBundle{
+			Model:            bytes.NewBuffer([]byte{}),
+			LexicalShortlist: bytes.NewBuffer([]byte{}),
+			Vocabulary:       bytes.NewBuffer([]byte{}),
+		},
+		Observer: observer,
+	})
+	if err == nil {
+		t.Fatalf("New should have failed")
+	}
+	if len(observer.created) != 1 {
+		t.Fatalf("expected 1 observed event, got %d", len(observer.created))
+	}
+	if event := observer.created[0]; event.Err == nil || event.Start.IsZero() {
+		t.Errorf("expected event with error and start time, got %+v", event)
+	}
+}
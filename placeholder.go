@@ -0,0 +1,513 @@
+package gobergamot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/KSpaceer/gobergamot/internal/mask"
+)
+
+var ErrPlaceholderLost = errors.New("placeholder is lost in translation")
+
+// PlaceholderLostError is returned if a placeholder protected with TranslationOptions.Placeholders
+// is missing in the translation. It matches ErrPlaceholderLost.
+type PlaceholderLostError struct {
+	// Placeholder missing in the translation
+	Placeholder string
+	// Text of the translation request
+	Text string
+}
+
+func (e *PlaceholderLostError) Error() string {
+	return fmt.Sprintf("%v: %q in %q", ErrPlaceholderLost, e.Placeholder, e.Text)
+}
+
+func (e *PlaceholderLostError) Unwrap() error {
+	return ErrPlaceholderLost
+}
+
+var (
+	// printfVerb matches printf verb at the start of the text
+	printfVerb = regexp.MustCompile(`^` + mask.PrintfVerb)
+)
+
+// placeholderMessage is a text with placeholders. It is translated in HTML mode with placeholders replaced
+// by img elements.
+type placeholderMessage struct {
+	parts []messagePart
+	// set if the message has any text to translate
+	hasText bool
+	// translation of the masked message
+	translation string
+}
+
+// messagePart is a text or a placeholder kept as is.
+type messagePart struct {
+	text        string
+	placeholder bool
+	// choice is set for ICU plural and select arguments
+	choice *choiceArgument
+	// term is set for glossary terms
+	term *GlossaryEntry
+}
+
+// masked reports if the part is replaced by img element in the translated text.
+func (p messagePart) masked() bool {
+	return p.placeholder || p.choice != nil || p.term != nil
+}
+
+// choiceArgument is an ICU plural, selectordinal or select argument, e.g. {count, plural, one {# file} other {# files}}.
+// Branches are translated separately.
+type choiceArgument struct {
+	// prefixes[i] is the raw text preceding branches[i], e.g. "{count, plural, one {" and "} other {"
+	prefixes []string
+	branches []*placeholderMessage
+	// suffix is the raw text after the last branch, e.g. "}}"
+	suffix string
+}
+
+// messageParser splits texts into messages.
+type messageParser struct {
+	// placeholders defines if placeholders are protected
+	placeholders bool
+	glossary     *Glossary
+	isHTML       bool
+}
+
+// htmlTag matches HTML tags, which glossary terms are not searched in
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// parse finds placeholders in the text: printf verbs, Go template actions and mustache tokens,
+// ICU arguments, # inside plural branches and ICU quoted literals like '{' (which are kept as is).
+// Then it finds glossary terms in the rest of the text.
+func (p messageParser) parse(text string, inPlural bool) *placeholderMessage {
+	m := new(placeholderMessage)
+	var textStart int
+	addPart := func(start, end int, part messagePart) {
+		p.addText(m, text[textStart:start])
+		m.parts = append(m.parts, part)
+		textStart = end
+	}
+
+	for i := 0; p.placeholders && i < len(text); {
+		switch text[i] {
+		case '\'':
+			if i+1 < len(text) && text[i+1] == '\'' {
+				// escaped apostrophe
+				i += 2
+				continue
+			}
+			if end := quotedEnd(text, i, inPlural); end > 0 {
+				addPart(i, end, messagePart{text: text[i:end], placeholder: true})
+				i = end
+				continue
+			}
+		case '{':
+			end := matchingBrace(text, i, inPlural)
+			if end < 0 {
+				break
+			}
+			raw := text[i : end+1]
+			part := messagePart{text: raw, placeholder: true}
+			if choice := p.parseChoice(raw, inPlural); choice != nil {
+				part = messagePart{text: raw, choice: choice}
+			}
+			addPart(i, end+1, part)
+			i = end + 1
+			continue
+		case '%':
+			if loc := printfVerb.FindStringIndex(text[i:]); loc != nil {
+				addPart(i, i+loc[1], messagePart{text: text[i : i+loc[1]], placeholder: true})
+				i += loc[1]
+				continue
+			}
+		case '#':
+			if inPlural {
+				addPart(i, i+1, messagePart{text: "#", placeholder: true})
+			}
+		}
+		i++
+	}
+	p.addText(m, text[textStart:])
+	return m
+}
+
+// addText adds the text to the message, separating glossary terms from it.
+func (p messageParser) addText(m *placeholderMessage, text string) {
+	addText := func(text string) {
+		if text == "" {
+			return
+		}
+		m.parts = append(m.parts, messagePart{text: text})
+		if strings.TrimSpace(text) != "" {
+			m.hasText = true
+		}
+	}
+	// glossary terms are not searched inside HTML tags
+	var tags [][]int
+	if p.isHTML {
+		tags = htmlTag.FindAllStringIndex(text, -1)
+	}
+	tags = append(tags, []int{len(text), len(text)})
+
+	var last int
+	for _, tag := range tags {
+		fragment := text[last:tag[0]]
+		var textStart int
+		for _, loc := range p.glossary.find(fragment) {
+			addText(fragment[textStart:loc[0]])
+			entry := p.glossary.entry(fragment[loc[0]:loc[1]])
+			m.parts = append(m.parts, messagePart{text: fragment[loc[0]:loc[1]], term: &entry})
+			textStart = loc[1]
+		}
+		addText(fragment[textStart:])
+		addText(text[tag[0]:tag[1]])
+		last = tag[1]
+	}
+}
+
+// matchingBrace returns the index of the brace closing the one at start or -1 if there is none.
+// Braces inside ICU quoted literals are skipped.
+func matchingBrace(text string, start int, inPlural bool) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '\'':
+			if i+1 < len(text) && text[i+1] == '\'' {
+				i++
+			} else if end := quotedEnd(text, i, inPlural); end > 0 {
+				i = end - 1
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// quotedEnd returns the end of ICU quoted literal starting with the apostrophe at start or -1 if the apostrophe
+// doesn't start one. Like in ICU, only apostrophes followed by a special character start literals
+// ('{', '}', '|' and '#' in plural branches), so apostrophes in words like "don't" are kept as is.
+// Unclosed literal lasts till the end of the text.
+func quotedEnd(text string, start int, inPlural bool) int {
+	if start+1 >= len(text) {
+		return -1
+	}
+	switch text[start+1] {
+	case '{', '}', '|':
+	case '#':
+		if !inPlural {
+			return -1
+		}
+	default:
+		return -1
+	}
+	for i := start + 1; i < len(text); i++ {
+		if text[i] != '\'' {
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '\'' {
+			// escaped apostrophe inside the literal
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(text)
+}
+
+// parseChoice parses ICU choice argument. Returns nil if the argument is not a valid plural,
+// selectordinal or select argument.
+func (p messageParser) parseChoice(raw string, inPlural bool) *choiceArgument {
+	fields := strings.SplitN(raw[1:len(raw)-1], ",", 3)
+	if len(fields) != 3 {
+		return nil
+	}
+	switch strings.TrimSpace(fields[1]) {
+	case "plural", "selectordinal":
+		inPlural = true
+	case "select":
+	default:
+		return nil
+	}
+
+	var (
+		choice = new(choiceArgument)
+		// start of the raw text preceding the next branch
+		prev int
+		// skipping the argument name and type
+		i = len(fields[0]) + len(fields[1]) + 3
+	)
+	for {
+		for i < len(raw) && isSpace(raw[i]) {
+			i++
+		}
+		// selector, e.g. "one", "=0" or "other"
+		selectorStart := i
+		for i < len(raw) && raw[i] != '{' && raw[i] != '}' && !isSpace(raw[i]) {
+			i++
+		}
+		selector := raw[selectorStart:i]
+		for i < len(raw) && isSpace(raw[i]) {
+			i++
+		}
+		if strings.HasPrefix(selector, "offset:") && choice.branches == nil && raw[i] != '{' {
+			continue
+		}
+		if i >= len(raw)-1 || raw[i] != '{' {
+			break
+		}
+		end := matchingBrace(raw, i, inPlural)
+		if end < 0 || end == len(raw)-1 {
+			return nil
+		}
+		choice.prefixes = append(choice.prefixes, raw[prev:i+1])
+		choice.branches = append(choice.branches, p.parse(raw[i+1:end], inPlural))
+		prev = end
+		i = end + 1
+	}
+	if len(choice.branches) == 0 || strings.TrimSpace(raw[prev+1:len(raw)-1]) != "" {
+		return nil
+	}
+	choice.suffix = raw[prev:]
+	return choice
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// units returns the message and messages of choice branches which have text to translate.
+func (m *placeholderMessage) units(units []*placeholderMessage) []*placeholderMessage {
+	if m.hasText {
+		units = append(units, m)
+	}
+	for _, part := range m.parts {
+		if part.choice != nil {
+			for _, branch := range part.choice.branches {
+				units = branch.units(units)
+			}
+		}
+	}
+	return units
+}
+
+// mask returns the HTML text to translate. Placeholders are replaced by img elements (see mask.Void),
+// which are numbered in order of the masked parts.
+func (m *placeholderMessage) mask(isHTML bool) string {
+	var (
+		b      strings.Builder
+		masked int
+	)
+	for _, part := range m.parts {
+		switch {
+		case part.masked():
+			b.WriteString(mask.Void(masked))
+			masked++
+		case isHTML:
+			b.WriteString(part.text)
+		default:
+			b.WriteString(mask.Escape(part.text))
+		}
+	}
+	return b.String()
+}
+
+// maskedParts returns the parts replaced by img elements in the translated text.
+func (m *placeholderMessage) maskedParts() []messagePart {
+	var parts []messagePart
+	for _, part := range m.parts {
+		if part.masked() {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// messageRestorer puts placeholders and glossary terms back into translations of messages.
+type messageRestorer struct {
+	isHTML bool
+	// text of the translation request
+	text string
+	// glossary entries applied to the text
+	applied []GlossaryEntry
+}
+
+// restore returns the translated message with placeholders put back. Messages without text are kept as is.
+func (r *messageRestorer) restore(m *placeholderMessage) (string, error) {
+	var b strings.Builder
+	if !m.hasText {
+		for _, part := range m.parts {
+			text, err := r.restorePart(part)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(text)
+		}
+		return b.String(), nil
+	}
+
+	parts := m.maskedParts()
+	text := func(text string) string {
+		if r.isHTML {
+			return text
+		}
+		return mask.Unescape(text)
+	}
+	restored, lost, err := mask.Unmask(m.translation, len(parts), func(n int) (string, error) {
+		return r.restorePart(parts[n])
+	}, text)
+	if err != nil {
+		return "", err
+	}
+	if len(lost) > 0 {
+		return "", &PlaceholderLostError{Placeholder: parts[lost[0]].text, Text: r.text}
+	}
+	return restored, nil
+}
+
+func (r *messageRestorer) restorePart(p messagePart) (string, error) {
+	switch {
+	case p.term != nil:
+		if !slices.Contains(r.applied, *p.term) {
+			r.applied = append(r.applied, *p.term)
+		}
+		if p.term.Target == "" {
+			return p.text, nil
+		}
+		if r.isHTML {
+			return mask.Escape(p.term.Target), nil
+		}
+		return p.term.Target, nil
+	case p.choice != nil:
+		var b strings.Builder
+		for i, branch := range p.choice.branches {
+			text, err := r.restore(branch)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(p.choice.prefixes[i])
+			b.WriteString(text)
+		}
+		b.WriteString(p.choice.suffix)
+		return b.String(), nil
+	default:
+		return p.text, nil
+	}
+}
+
+// translateProtected translates the requests, protecting placeholders of requests with Placeholders option
+// and terms of the glossary. The glossary of the request options is used if it is set. Such requests are split
+// into messages which are translated in HTML mode, so text and result convert translations of the messages
+// into results.
+func translateProtected[T any](
+	requests []TranslationRequest,
+	glossary *Glossary,
+	translate func(requests []TranslationRequest) ([]T, error),
+	text func(T) string,
+	result func(request TranslationRequest, text string, applied []GlossaryEntry) T,
+) ([]T, error) {
+	parser := func(request TranslationRequest) (messageParser, bool) {
+		p := messageParser{
+			placeholders: request.Options.Placeholders,
+			glossary:     glossary,
+			isHTML:       request.Options.HTML,
+		}
+		if request.Options.Glossary != nil {
+			p.glossary = request.Options.Glossary
+		}
+		return p, p.placeholders || p.glossary != nil
+	}
+	if !slices.ContainsFunc(requests, func(r TranslationRequest) bool {
+		_, protected := parser(r)
+		return protected
+	}) {
+		return translate(requests)
+	}
+
+	var (
+		expanded []TranslationRequest
+		messages = make([]*placeholderMessage, len(requests))
+		units    = make([][]*placeholderMessage, len(requests))
+		// index of the first expanded request of every request
+		offsets = make([]int, len(requests))
+	)
+	for i, request := range requests {
+		offsets[i] = len(expanded)
+		p, protected := parser(request)
+		if !protected {
+			expanded = append(expanded, request)
+			continue
+		}
+		message := p.parse(request.Text, false)
+		if !slices.ContainsFunc(message.parts, messagePart.masked) {
+			// nothing to protect, so the request is translated as is
+			expanded = append(expanded, request)
+			continue
+		}
+		messages[i] = message
+		units[i] = message.units(nil)
+		for _, unit := range units[i] {
+			unitRequest := request
+			unitRequest.Text = unit.mask(request.Options.HTML)
+			unitRequest.Options = TranslationOptions{HTML: true}
+			expanded = append(expanded, unitRequest)
+		}
+	}
+
+	var translated []T
+	if len(expanded) > 0 {
+		var err error
+		translated, err = translate(expanded)
+		if err != nil {
+			return nil, err
+		}
+		if len(translated) != len(expanded) {
+			return nil, fmt.Errorf("expected %d translation results, got %d", len(expanded), len(translated))
+		}
+	}
+
+	output := make([]T, len(requests))
+	for i, request := range requests {
+		if messages[i] == nil {
+			output[i] = translated[offsets[i]]
+			continue
+		}
+		for j, unit := range units[i] {
+			unit.translation = text(translated[offsets[i]+j])
+		}
+		r := messageRestorer{isHTML: request.Options.HTML, text: request.Text}
+		restored, err := r.restore(messages[i])
+		if err != nil {
+			return nil, err
+		}
+		output[i] = result(request, restored, r.applied)
+	}
+	return output, nil
+}
+
+func translatedText(text string) string {
+	return text
+}
+
+func protectedText(_ TranslationRequest, text string, _ []GlossaryEntry) string {
+	return text
+}
+
+func resultText(result TranslationResult) string {
+	return result.Text
+}
+
+// protectedResult is a result of request with protected placeholders or glossary terms.
+// Sentences of the messages don't match the whole text, so only texts and applied glossary entries are set.
+func protectedResult(request TranslationRequest, text string, applied []GlossaryEntry) TranslationResult {
+	return TranslationResult{Source: request.Text, Text: text, Glossary: applied}
+}
@@ -0,0 +1,330 @@
+package gobergamot
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMessageParser(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// masked texts of the message units
+		expected []string
+		// masked parts of the message
+		placeholders []string
+	}{
+		{
+			name:         "printf verbs and templates",
+			text:         "%s files in %[2]d folders of {{.User.Name}}, 100%% done",
+			expected:     []string{`<img id="p0"> files in <img id="p1"> folders of <img id="p2">, 100<img id="p3"> done`},
+			placeholders: []string{"%s", "%[2]d", "{{.User.Name}}", "%%"},
+		},
+		{
+			name: "ICU plural",
+			text: "Hello, {name}! You have {count, plural, =0 {no messages} one {# message} other {# messages}}.",
+			expected: []string{
+				`Hello, <img id="p0">! You have <img id="p1">.`,
+				`no messages`,
+				`<img id="p0"> message`,
+				`<img id="p0"> messages`,
+			},
+			placeholders: []string{"{name}", "{count, plural, =0 {no messages} one {# message} other {# messages}}"},
+		},
+		{
+			name: "nested ICU arguments",
+			text: "{gender, select, female {{count, plural, one {She has # file} other {She has # files}}} other {They have {count} files}}",
+			expected: []string{
+				`She has <img id="p0"> file`,
+				`She has <img id="p0"> files`,
+				`They have <img id="p0"> files`,
+			},
+			placeholders: []string{
+				"{gender, select, female {{count, plural, one {She has # file} other {She has # files}}} other {They have {count} files}}",
+			},
+		},
+		{
+			name:         "ICU plural with offset",
+			text:         "{count, plural, offset:1 =0 {nobody} other {you and # others}}",
+			expected:     []string{`nobody`, `you and <img id="p0"> others`},
+			placeholders: []string{"{count, plural, offset:1 =0 {nobody} other {you and # others}}"},
+		},
+		{
+			name:         "escaped apostrophes",
+			text:         "It''s '{name}' and {name}'s file, isn't it?",
+			expected:     []string{`It''s <img id="p0"> and <img id="p1">'s file, isn't it?`},
+			placeholders: []string{"'{name}'", "{name}"},
+		},
+		{
+			name: "quoted braces in plural branch",
+			text: "{count, plural, one {'{'#'}' item} other {'#' is # items}}",
+			expected: []string{
+				`<img id="p0"><img id="p1"><img id="p2"> item`,
+				`<img id="p0"> is <img id="p1"> items`,
+			},
+			placeholders: []string{"{count, plural, one {'{'#'}' item} other {'#' is # items}}"},
+		},
+		{
+			name:         "unclosed quoted literal",
+			text:         "Use '{ to start",
+			expected:     []string{`Use <img id="p0">`},
+			placeholders: []string{"'{ to start"},
+		},
+		{
+			name:         "unbalanced braces",
+			text:         "Hello {name, you have } messages {",
+			expected:     []string{`Hello <img id="p0"> messages {`},
+			placeholders: []string{"{name, you have }"},
+		},
+		{
+			name: "unclosed ICU plural",
+			text: "{count, plural, one {# item} other {# items}",
+			expected: []string{
+				`{count, plural, one <img id="p0"> other <img id="p1">`,
+			},
+			placeholders: []string{"{# item}", "{# items}"},
+		},
+		{
+			name:         "invalid ICU argument type",
+			text:         "{count, number, one {# item}}",
+			expected:     nil,
+			placeholders: []string{"{count, number, one {# item}}"},
+		},
+		{
+			name:         "HTML special characters",
+			text:         "a < b & {c}",
+			expected:     []string{`a &lt; b &amp; <img id="p0">`},
+			placeholders: []string{"{c}"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := messageParser{placeholders: true}.parse(tc.text, false)
+
+			var masked []string
+			for _, unit := range m.units(nil) {
+				masked = append(masked, unit.mask(false))
+			}
+			if !reflect.DeepEqual(masked, tc.expected) {
+				t.Errorf("expected masked units %q, got %q", tc.expected, masked)
+			}
+
+			var placeholders []string
+			for _, part := range m.maskedParts() {
+				placeholders = append(placeholders, part.text)
+			}
+			if !reflect.DeepEqual(placeholders, tc.placeholders) {
+				t.Errorf("expected placeholders %q, got %q", tc.placeholders, placeholders)
+			}
+
+			var joined strings.Builder
+			for _, part := range m.parts {
+				joined.WriteString(part.text)
+			}
+			if joined.String() != tc.text {
+				t.Errorf("parts %q don't make up the text", joined.String())
+			}
+		})
+	}
+}
+
+var fakeTag = regexp.MustCompile(`<[^>]*>`)
+
+// upperCase "translates" HTML texts by converting them to upper case, keeping HTML tags.
+func upperCase(text string) string {
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, loc := range fakeTag.FindAllStringIndex(text, -1) {
+		b.WriteString(strings.ToUpper(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(strings.ToUpper(text[last:]))
+	return b.String()
+}
+
+func TestTranslateProtected(t *testing.T) {
+	glossary, err := NewGlossary(GlossaryEntry{Source: "Firefox"}, GlossaryEntry{Source: "browser", Target: "Браузер"})
+	if err != nil {
+		t.Fatalf("failed to create glossary: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		request   TranslationRequest
+		glossary  *Glossary
+		translate func(text string) string
+		expected  string
+		// expected requests sent to the translator
+		expectedTexts []string
+		wantErr       error
+	}{
+		{
+			name:          "not protected",
+			request:       TranslationRequest{Text: "hello, {name}"},
+			translate:     upperCase,
+			expected:      "HELLO, {NAME}",
+			expectedTexts: []string{"hello, {name}"},
+		},
+		{
+			name: "placeholders",
+			request: TranslationRequest{
+				Text:    "Hello, {name}! You have {count, plural, one {# message} other {# messages}} & %d more",
+				Options: TranslationOptions{Placeholders: true},
+			},
+			translate: upperCase,
+			expected:  "HELLO, {name}! YOU HAVE {count, plural, one {# MESSAGE} other {# MESSAGES}} & %d MORE",
+			expectedTexts: []string{
+				`Hello, <img id="p0">! You have <img id="p1"> &amp; <img id="p2"> more`,
+				`<img id="p0"> message`,
+				`<img id="p0"> messages`,
+			},
+		},
+		{
+			name: "reordered placeholders",
+			request: TranslationRequest{
+				Text:    "%s of %d",
+				Options: TranslationOptions{Placeholders: true},
+			},
+			translate: func(text string) string {
+				return `<img id="p1"> ИЗ <img id="p0">`
+			},
+			expected:      "%d ИЗ %s",
+			expectedTexts: []string{`<img id="p0"> of <img id="p1">`},
+		},
+		{
+			name: "repeated and made up placeholders",
+			request: TranslationRequest{
+				Text:    "Hello, {name}!",
+				Options: TranslationOptions{Placeholders: true},
+			},
+			translate: func(text string) string {
+				return `ПРИВЕТ, <img id="p0"><img id="p0"><img id="p7">!`
+			},
+			expected:      "ПРИВЕТ, {name}!",
+			expectedTexts: []string{`Hello, <img id="p0">!`},
+		},
+		{
+			name: "lost placeholder",
+			request: TranslationRequest{
+				Text:    "Hello, {name}! You have %d messages",
+				Options: TranslationOptions{Placeholders: true},
+			},
+			translate: func(text string) string {
+				return `ПРИВЕТ, <img id="p0">! У ВАС СООБЩЕНИЯ`
+			},
+			expectedTexts: []string{`Hello, <img id="p0">! You have <img id="p1"> messages`},
+			wantErr: &PlaceholderLostError{
+				Placeholder: "%d",
+				Text:        "Hello, {name}! You have %d messages",
+			},
+		},
+		{
+			name: "lost placeholder in plural branch",
+			request: TranslationRequest{
+				Text:    "{count, plural, one {# message} other {# messages}}",
+				Options: TranslationOptions{Placeholders: true},
+			},
+			translate: func(text string) string {
+				return strings.ReplaceAll(upperCase(text), `<img id="p0">`, "")
+			},
+			expectedTexts: []string{`<img id="p0"> message`, `<img id="p0"> messages`},
+			wantErr: &PlaceholderLostError{
+				Placeholder: "#",
+				Text:        "{count, plural, one {# message} other {# messages}}",
+			},
+		},
+		{
+			name: "only placeholders",
+			request: TranslationRequest{
+				Text:    "{count, plural, one {#} other {#}}",
+				Options: TranslationOptions{Placeholders: true},
+			},
+			translate: upperCase,
+			expected:  "{count, plural, one {#} other {#}}",
+		},
+		{
+			name:     "glossary in HTML",
+			request:  TranslationRequest{Text: `<b title="browser">Firefox</b> is a browser`, Options: TranslationOptions{HTML: true}},
+			glossary: glossary,
+			translate: func(text string) string {
+				return upperCase(text)
+			},
+			expected:      `<b title="browser">Firefox</b> IS A Браузер`,
+			expectedTexts: []string{`<b title="browser"><img id="p0"></b> is a <img id="p1">`},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var texts []string
+			translate := func(requests []TranslationRequest) ([]string, error) {
+				outputs := make([]string, len(requests))
+				for i, request := range requests {
+					texts = append(texts, request.Text)
+					outputs[i] = tc.translate(request.Text)
+				}
+				return outputs, nil
+			}
+			outputs, err := translateProtected([]TranslationRequest{tc.request}, tc.glossary, translate, translatedText, protectedText)
+			if !reflect.DeepEqual(texts, tc.expectedTexts) {
+				t.Errorf("expected translated texts %q, got %q", tc.expectedTexts, texts)
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, ErrPlaceholderLost) {
+					t.Fatalf("expected ErrPlaceholderLost, got %v", err)
+				}
+				var lostErr *PlaceholderLostError
+				if !errors.As(err, &lostErr) || !reflect.DeepEqual(lostErr, tc.wantErr) {
+					t.Errorf("expected error %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to translate: %v", err)
+			}
+			if len(outputs) != 1 || outputs[0] != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, outputs)
+			}
+		})
+	}
+}
+
+func TestTranslateProtected_Batch(t *testing.T) {
+	requests := []TranslationRequest{
+		{Text: "plain {text}"},
+		{Text: "{count, plural, one {# file} other {# files}}", Options: TranslationOptions{Placeholders: true}},
+		{Text: "no placeholders", Options: TranslationOptions{Placeholders: true}},
+		{Text: "%s left", Options: TranslationOptions{Placeholders: true}},
+	}
+	var calls int
+	outputs, err := translateProtected(requests, nil, func(requests []TranslationRequest) ([]string, error) {
+		calls++
+		outputs := make([]string, len(requests))
+		for i := range requests {
+			outputs[i] = upperCase(requests[i].Text)
+		}
+		return outputs, nil
+	}, translatedText, protectedText)
+	if err != nil {
+		t.Fatalf("failed to translate: %v", err)
+	}
+	expected := []string{"PLAIN {TEXT}", "{count, plural, one {# FILE} other {# FILES}}", "NO PLACEHOLDERS", "%s LEFT"}
+	if !reflect.DeepEqual(outputs, expected) {
+		t.Errorf("expected %q, got %q", expected, outputs)
+	}
+	if calls != 1 {
+		t.Errorf("expected requests to be translated in a single call, got %d calls", calls)
+	}
+
+	_, err = translateProtected(requests, nil, func(requests []TranslationRequest) ([]string, error) {
+		return make([]string, len(requests)-1), nil
+	}, translatedText, protectedText)
+	if err == nil {
+		t.Errorf("expected error for missing translation results")
+	}
+}
@@ -0,0 +1,65 @@
+package gobergamot_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/internal/wasm"
+)
+
+func TestTranslator_Placeholders(t *testing.T) {
+	ctx := context.Background()
+
+	stderr := new(strings.Builder)
+	translator, err := gobergamot.New(ctx, gobergamot.Config{
+		CompileConfig: wasm.CompileConfig{Stderr: stderr},
+		FilesBundle:   testBundle(t),
+	})
+	if err != nil {
+		t.Fatalf("failed to create translator: %v\n\nstderr: %s", err, stderr.String())
+	}
+	defer func() {
+		if err := translator.Close(ctx); err != nil {
+			t.Fatalf("failed to close translator: %v", err)
+		}
+	}()
+
+	tests := []struct {
+		text         string
+		placeholders []string
+	}{
+		{
+			text: "Hello, {name}! You have {count, plural, one {# new message} other {# new messages}}.",
+			placeholders: []string{
+				"{name}", "{count, plural, one {#", "} other {#", "}}",
+			},
+		},
+		{
+			text:         "%s files in %[2]d folders",
+			placeholders: []string{"%s", "%[2]d"},
+		},
+		{
+			text:         "Welcome, {{.User.Name}}!",
+			placeholders: []string{"{{.User.Name}}"},
+		},
+	}
+	for _, tc := range tests {
+		output, err := translator.Translate(ctx, gobergamot.TranslationRequest{
+			Text:    tc.text,
+			Options: gobergamot.TranslationOptions{Placeholders: true},
+		})
+		if err != nil {
+			t.Fatalf("failed to translate %q: %v", tc.text, err)
+		}
+		if output == tc.text {
+			t.Errorf("text %q is not translated", tc.text)
+		}
+		for _, placeholder := range tc.placeholders {
+			if !strings.Contains(output, placeholder) {
+				t.Errorf("placeholder %q is lost in translation of %q: %q", placeholder, tc.text, output)
+			}
+		}
+	}
+}
@@ -2,31 +2,112 @@ package gobergamot
 
 import (
 	"bytes"
+	"container/list"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
 
 	"github.com/KSpaceer/gobergamot/internal/errgroup"
+	"github.com/KSpaceer/gobergamot/internal/wasm"
 )
 
-var ErrClosed = errors.New("pool closed")
+var (
+	ErrClosed                = errors.New("pool closed")
+	ErrWorkerPanic           = errors.New("worker panic")
+	ErrWorkerRecoveryFailure = errors.New("failed to recover worker")
+	ErrFilesReleased         = errors.New("pool files are released")
+)
+
+// DefaultIdleTimeout is a default time after which idle workers of elastic pool are closed.
+const DefaultIdleTimeout = time.Minute
+
+const (
+	// recoveryMinBackoff and recoveryMaxBackoff limit delay between attempts to recreate worker's Translator
+	recoveryMinBackoff = 100 * time.Millisecond
+	recoveryMaxBackoff = 10 * time.Second
+)
 
 type PoolConfig struct {
 	Config
+	// PoolSize is a number of workers in the pool of fixed size. Must be zero if MaxSize is set.
 	PoolSize uint
+
+	// MaxSize makes the pool elastic: it starts with MinSize workers and spawns extra ones
+	// when there is no free worker for a request, up to MaxSize workers.
+	// Extra workers are closed after IdleTimeout of inactivity (DefaultIdleTimeout by default).
+	// If MinSize is zero, the pool starts without workers, so model files are not checked until the first request.
+	// If the pool has no workers and fails to spawn one, queued requests fail with the error of the spawn.
+	MinSize     uint
+	MaxSize     uint
+	IdleTimeout time.Duration
+
+	// MaxQueueDepth limits number of requests waiting for a free worker. If the queue is full,
+	// requests fail with ErrQueueFull. Zero means that the queue is unbounded.
+	// Requests are taken from the queue by their priority set with WithPriority.
+	MaxQueueDepth uint
+
+	// BatchWindow enables batching of concurrent requests: a worker taking a request waits up to BatchWindow
+	// for other queued requests and translates them all with a single call, which is much more efficient
+	// for many small texts. If a batch breaks the worker, its requests are retried one by one by the recovered
+	// worker, so only the request causing the failure fails. Zero disables batching.
+	BatchWindow time.Duration
+	// BatchMaxWords limits number of words in a batch, similar to Bergamot mini-batch-words option.
+	// A request exceeding the limit is translated alone. Defaults to DefaultBatchMaxWords.
+	BatchMaxWords uint
+
+	// ReleaseFiles makes the pool drop files data once the initial workers are created, so only workers' WASM memories
+	// keep the models. Such pool can't create new translators: it must have fixed size, and workers encountering
+	// fatal errors are stopped instead of being recovered (OnWorkerFailure is called with ErrFilesReleased).
+	// When all workers are stopped, requests fail with ErrFilesReleased.
+	//
+	// If files in FilesBundle are *os.File, the pool maps them into memory instead of reading, so their data
+	// is read lazily from page cache and does not take Go heap even without ReleaseFiles.
+	ReleaseFiles bool
+
+	// OnWorkerFailure is called when a worker encounters a fatal error (WASM trap, module exit or panic)
+	// and its Translator is going to be replaced with a new one. It is also called with error wrapping
+	// ErrWorkerRecoveryFailure if the worker fails to create a new Translator, in this case the worker
+	// retries until the pool is closed. Called from worker goroutine, so it should not block.
+	OnWorkerFailure func(workerID uint, err error)
 }
 
 func (cfg PoolConfig) Validate() error {
 	var err error
-	if cfg.PoolSize == 0 {
+	switch {
+	case cfg.MaxSize == 0 && cfg.MinSize != 0:
+		err = errors.Join(err, errors.New("MinSize is set without MaxSize"))
+	case cfg.MaxSize == 0 && cfg.PoolSize == 0:
 		err = errors.Join(err, errors.New("zero pool size"))
+	case cfg.MaxSize != 0 && cfg.PoolSize != 0:
+		err = errors.Join(err, errors.New("PoolSize can't be set along with MaxSize"))
+	case cfg.MinSize > cfg.MaxSize:
+		err = errors.Join(err, errors.New("MinSize is greater than MaxSize"))
+	}
+	if cfg.IdleTimeout < 0 {
+		err = errors.Join(err, errors.New("negative idle timeout"))
+	}
+	if cfg.ReleaseFiles && cfg.MaxSize != 0 {
+		err = errors.Join(err, errors.New("ReleaseFiles can't be used with elastic pool"))
+	}
+	if cfg.BatchWindow < 0 {
+		err = errors.Join(err, errors.New("negative batch window"))
 	}
 	return errors.Join(err, cfg.Config.Validate())
 }
 
 // NewPool compiles Translator instances and runs them as workers.
+// Elastic pool (with MaxSize set) starts MinSize workers.
 func NewPool(ctx context.Context, cfg PoolConfig) (*Pool, error) {
 	err := cfg.Validate()
 	if err != nil {
@@ -39,28 +120,51 @@ func NewPool(ctx context.Context, cfg PoolConfig) (*Pool, error) {
 		// using cache to speed up workers creation
 		cfg.Config.WASMCache = wazero.NewCompilationCache()
 	}
+	if cfg.MaxSize == 0 {
+		cfg.MinSize, cfg.MaxSize = cfg.PoolSize, cfg.PoolSize
+	} else if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	cfg.Observer = observerOrNop(cfg.Observer)
+	if cfg.BatchMaxWords == 0 {
+		cfg.BatchMaxWords = DefaultBatchMaxWords
+	}
 	p := &Pool{
-		cfg:     cfg,
-		reqChan: make(chan workerRequest),
-		done:    make(chan struct{}),
-		eg:      errgroup.New(),
+		cfg:   cfg,
+		queue: newRequestQueue(cfg.MaxQueueDepth),
+		done:  make(chan struct{}),
+		eg:    errgroup.New(),
 	}
 	// converting Config FileBundle into byte slices
 	// to share between workers to read
-	if err = filesToBytes(p); err != nil {
+	if p.files, err = readBundleBytes(cfg.FilesBundle); err != nil {
 		return nil, err
 	}
+	if cfg.Pivot != nil {
+		pivotFiles, err := readBundleBytes(*cfg.Pivot)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("pivot: %w", err), p.releaseFiles())
+		}
+		p.pivotFiles = &pivotFiles
+	}
 
 	translators, err := p.buildTranslators(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to setup translators: %w", err)
+		return nil, errors.Join(fmt.Errorf("failed to setup translators: %w", err), p.releaseFiles())
+	}
+	if cfg.ReleaseFiles {
+		if err := p.releaseFiles(); err != nil {
+			return nil, err
+		}
+		p.filesReleased = true
 	}
 
+	p.mu.Lock()
 	for i := range translators {
-		p.eg.Go(func() error {
-			return p.runWorker(translators[i])
-		})
+		p.startWorker(uint(i), translators[i])
 	}
+	p.nextID = uint(len(translators))
+	p.mu.Unlock()
 
 	return p, nil
 }
@@ -68,25 +172,51 @@ func NewPool(ctx context.Context, cfg PoolConfig) (*Pool, error) {
 type Pool struct {
 	cfg PoolConfig
 
-	reqChan chan workerRequest
+	queue *requestQueue
+	// number of workers waiting for requests
+	idleWorkers atomic.Int64
+	// number of workers collecting a batch, they take arriving requests too
+	collectingWorkers atomic.Int64
 
 	eg   errgroup.Errgroup
 	done chan struct{}
 
-	modelBytes      []byte
-	shortlistBytes  []byte
-	vocabularyBytes []byte
+	// mu guards the fields below
+	mu sync.Mutex
+	// number of running workers, including the ones creating their translators
+	workers uint
+	// worker ID to assign to the next started worker
+	nextID uint
+	closed bool
+
+	files      bundleBytes
+	pivotFiles *bundleBytes
+	// set if files are released after creation of the initial workers
+	filesReleased bool
 }
 
 type workerRequest struct {
 	ctx      context.Context
 	reqs     []TranslationRequest
+	detailed bool
 	respChan chan workerResponse
+
+	// position of the request in the queue, nil if the request is not queued
+	elem  *list.Element
+	level *list.List
+	// time the request was taken from the queue by a worker
+	takenAt time.Time
 }
 
 type workerResponse struct {
 	outputs []string
+	results []TranslationResult
 	err     error
+
+	// information about processing of the request for Observer
+	workerID  uint
+	batchSize int
+	takenAt   time.Time
 }
 
 // Translate is similar to Translator.Translate except the request is asynchronously given
@@ -105,36 +235,109 @@ func (p *Pool) Translate(ctx context.Context, request TranslationRequest) (strin
 // TranslateMultiple is similar to Translator.TranslateMultiple except the requests are asynchronously given
 // to any free worker in the pool.
 func (p *Pool) TranslateMultiple(ctx context.Context, requests ...TranslationRequest) ([]string, error) {
-	req := workerRequest{
+	resp := p.dispatch(ctx, requests, false)
+	return resp.outputs, resp.err
+}
+
+// TranslateDetailed is similar to Translator.TranslateDetailed except the request is asynchronously given
+// to any free worker in the pool.
+func (p *Pool) TranslateDetailed(ctx context.Context, request TranslationRequest) (TranslationResult, error) {
+	results, err := p.TranslateMultipleDetailed(ctx, request)
+	if err != nil {
+		return TranslationResult{}, err
+	}
+	if len(results) < 1 {
+		return TranslationResult{}, fmt.Errorf("expected translation results to have at least 1 element")
+	}
+	return results[0], nil
+}
+
+// TranslateMultipleDetailed is similar to Translator.TranslateMultipleDetailed except the requests
+// are asynchronously given to any free worker in the pool.
+func (p *Pool) TranslateMultipleDetailed(
+	ctx context.Context,
+	requests ...TranslationRequest,
+) ([]TranslationResult, error) {
+	resp := p.dispatch(ctx, requests, true)
+	return resp.results, resp.err
+}
+
+func (p *Pool) dispatch(ctx context.Context, requests []TranslationRequest, detailed bool) workerResponse {
+	start := time.Now()
+	resp := p.process(ctx, requests, detailed)
+
+	event := DispatchEvent{
+		Start:     start,
+		Duration:  time.Since(start),
+		QueueWait: time.Since(start),
+		Priority:  PriorityFromContext(ctx),
+		Texts:     len(requests),
+		BatchSize: resp.batchSize,
+		WorkerID:  resp.workerID,
+		Err:       resp.err,
+	}
+	if resp.batchSize != 0 {
+		event.QueueWait = resp.takenAt.Sub(start)
+	}
+	p.cfg.Observer.ObserveDispatch(ctx, event)
+	return resp
+}
+
+// process puts the request into the queue and waits for the response.
+func (p *Pool) process(ctx context.Context, requests []TranslationRequest, detailed bool) workerResponse {
+	req := &workerRequest{
 		ctx:      ctx,
 		reqs:     requests,
+		detailed: detailed,
 		respChan: make(chan workerResponse, 1),
 	}
 	select {
 	case <-p.done:
-		return nil, fmt.Errorf("did not found available worker: %w", ErrClosed)
-	case <-ctx.Done():
-		return nil, fmt.Errorf("did not found available worker: %w", ctx.Err())
-	case p.reqChan <- req:
+		return workerResponse{err: fmt.Errorf("did not found available worker: %w", ErrClosed)}
+	default:
+	}
+	// failing fast instead of occupying a worker
+	if err := checkInputSize(requests, p.cfg.MaxInputBytes); err != nil {
+		return workerResponse{err: err}
+	}
+	queueLen, err := p.queue.push(req, PriorityFromContext(ctx))
+	if err != nil {
+		return workerResponse{err: err}
+	}
+	if queueLen > uint(max(p.idleWorkers.Load()+p.collectingWorkers.Load(), 0)) {
+		// all workers are busy
+		p.spawnWorker()
 	}
 
 	select {
 	case <-p.done:
-		return nil, fmt.Errorf("failed to wait response: %w", ErrClosed)
+		if p.queue.remove(req) {
+			return workerResponse{err: fmt.Errorf("did not found available worker: %w", ErrClosed)}
+		}
+		return workerResponse{err: fmt.Errorf("failed to wait response: %w", ErrClosed)}
 	case <-ctx.Done():
-		return nil, fmt.Errorf("failed to wait response: %w", ctx.Err())
+		if p.queue.remove(req) {
+			return workerResponse{err: fmt.Errorf("did not found available worker: %w", ctx.Err())}
+		}
+		return workerResponse{err: fmt.Errorf("failed to wait response: %w", ctx.Err())}
 	case resp := <-req.respChan:
-		return resp.outputs, resp.err
+		return resp
 	}
 }
 
-// Close closes existing Translator instances and waits for their completion
+// Close closes existing Translator instances and waits for their completion.
+// If the context is done before that, files are released once the workers are stopped.
 func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
 	close(p.done)
 
-	errCh := make(chan error)
+	errCh := make(chan error, 1)
 	go func() {
-		errCh <- p.eg.Wait()
+		err := p.eg.Wait()
+		// no worker uses the files now, so they are released even if Close has stopped waiting
+		errCh <- errors.Join(err, p.releaseFiles())
 	}()
 	select {
 	case <-ctx.Done():
@@ -144,32 +347,216 @@ func (p *Pool) Close(ctx context.Context) error {
 	}
 }
 
-func (p *Pool) runWorker(translator *Translator) error {
+// releaseFiles drops files data and unmaps the mapped files.
+func (p *Pool) releaseFiles() error {
+	err := p.files.release()
+	if p.pivotFiles != nil {
+		err = errors.Join(err, p.pivotFiles.release())
+	}
+	p.files, p.pivotFiles = bundleBytes{}, nil
+	return err
+}
+
+// Size returns current number of workers in the pool.
+func (p *Pool) Size() uint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.workers
+}
+
+// QueueLen returns number of requests waiting for a free worker.
+func (p *Pool) QueueLen() uint {
+	return p.queue.length()
+}
+
+// startWorker runs a worker with given translator. Must be called with p.mu locked.
+func (p *Pool) startWorker(id uint, translator *Translator) {
+	p.workers++
+	p.eg.Go(func() error {
+		return p.runWorker(id, translator)
+	})
+}
+
+// spawnWorker starts a new worker if the pool has not reached its maximum size.
+// The worker's translator is created asynchronously.
+func (p *Pool) spawnWorker() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || p.workers >= p.cfg.MaxSize {
+		return
+	}
+	if p.filesReleased {
+		// stopped workers can't be replaced
+		if p.workers == 0 {
+			p.failQueued(fmt.Errorf("did not found available worker: %w", ErrFilesReleased))
+		}
+		return
+	}
+	id := p.nextID
+	p.nextID++
+	p.workers++
+	p.eg.Go(func() error {
+		translator, err := p.newTranslator(context.Background(), id)
+		if err != nil {
+			err = fmt.Errorf("failed to start worker: %w", err)
+			p.mu.Lock()
+			p.workers--
+			if p.workers == 0 {
+				// nobody is going to take the queued requests
+				p.failQueued(err)
+			}
+			p.mu.Unlock()
+			p.reportFailure(id, err)
+			return nil
+		}
+		return p.runWorker(id, translator)
+	})
+}
+
+// failQueued responds to all the queued requests with the error.
+func (p *Pool) failQueued(err error) {
+	for _, req := range p.queue.drain() {
+		req.respChan <- workerResponse{err: err}
+	}
+}
+
+// releaseIdleWorker decrements number of workers if the pool can be shrunk.
+func (p *Pool) releaseIdleWorker() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.workers <= p.cfg.MinSize {
+		return false
+	}
+	p.workers--
+	return true
+}
+
+func (p *Pool) runWorker(id uint, translator *Translator) error {
+	// idle timer is used only by elastic pool
+	var idleTimer *time.Timer
+	if p.cfg.MinSize < p.cfg.MaxSize {
+		idleTimer = time.NewTimer(p.cfg.IdleTimeout)
+		defer idleTimer.Stop()
+	}
 	for {
+		var idle <-chan time.Time
+		if idleTimer != nil {
+			idle = idleTimer.C
+		}
+		p.idleWorkers.Add(1)
 		select {
 		case <-p.done:
+			p.idleWorkers.Add(-1)
 			return translator.Close(context.Background())
-		case req := <-p.reqChan:
-			var resp workerResponse
-			resp.outputs, resp.err = translator.TranslateMultiple(req.ctx, req.reqs...)
-			req.respChan <- resp
+		case <-idle:
+			p.idleWorkers.Add(-1)
+			if p.releaseIdleWorker() {
+				return translator.Close(context.Background())
+			}
+			idleTimer.Reset(p.cfg.IdleTimeout)
+		case <-p.queue.ready:
+			p.idleWorkers.Add(-1)
+			req, ok := p.queue.pop()
+			if !ok {
+				continue
+			}
+			batch := []*workerRequest{req}
+			if p.cfg.BatchWindow > 0 {
+				batch = p.collectBatch(req)
+			}
+			retry, err := handleBatch(id, translator, batch)
+			for {
+				if err != nil && isFatalError(err) {
+					p.reportFailure(id, err)
+					// module state is undefined, so just dropping the runtime with everything allocated in it
+					_ = translator.discard(context.Background())
+					if translator = p.recoverWorker(id); translator == nil {
+						for _, req := range retry {
+							req.respChan <- workerResponse{err: err}
+						}
+						return nil
+					}
+				}
+				if len(retry) == 0 {
+					break
+				}
+				// the batch has failed as a whole, so its requests are retried one by one
+				// to fail only the one causing the failure
+				_, err = handleBatch(id, translator, []*workerRequest{retry[0]})
+				retry = retry[1:]
+			}
+			if idleTimer != nil {
+				if !idleTimer.Stop() {
+					<-idleTimer.C
+				}
+				idleTimer.Reset(p.cfg.IdleTimeout)
+			}
 		}
 	}
 }
 
+// handleWorkerRequest translates texts from the request and converts a panic into fatal error.
+func handleWorkerRequest(translator *Translator, req *workerRequest) (resp workerResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			resp = workerResponse{err: fmt.Errorf("%w: %v", ErrWorkerPanic, r)}
+		}
+	}()
+	if req.detailed {
+		resp.results, resp.err = translator.TranslateMultipleDetailed(req.ctx, req.reqs...)
+	} else {
+		resp.outputs, resp.err = translator.TranslateMultiple(req.ctx, req.reqs...)
+	}
+	return resp
+}
+
+// recoverWorker creates a new Translator for the worker, retrying with backoff until it succeeds.
+// Returns nil if the pool is closed before that.
+func (p *Pool) recoverWorker(id uint) *Translator {
+	backoff := recoveryMinBackoff
+	for {
+		translator, err := p.newTranslator(context.Background(), id)
+		if err == nil {
+			return translator
+		}
+		p.reportFailure(id, fmt.Errorf("%w: %w", ErrWorkerRecoveryFailure, err))
+		if errors.Is(err, ErrFilesReleased) {
+			// the worker can't be recovered, so just stopping it
+			p.mu.Lock()
+			p.workers--
+			if p.workers == 0 {
+				// requests arriving later are failed by spawnWorker
+				p.failQueued(fmt.Errorf("did not found available worker: %w", ErrFilesReleased))
+			}
+			p.mu.Unlock()
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-p.done:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, recoveryMaxBackoff)
+	}
+}
+
+func (p *Pool) reportFailure(id uint, err error) {
+	if p.cfg.OnWorkerFailure != nil {
+		p.cfg.OnWorkerFailure(id, err)
+	}
+}
+
 func (p *Pool) buildTranslators(ctx context.Context) ([]*Translator, error) {
 	eg := errgroup.New()
 
-	translators := make([]*Translator, p.cfg.PoolSize)
-	for i := uint(0); i < p.cfg.PoolSize; i++ {
+	translators := make([]*Translator, p.cfg.MinSize)
+	for i := uint(0); i < p.cfg.MinSize; i++ {
 		i := i
 		eg.Go(func() error {
-			cfg := p.cfg.Config
-			cfg.Model = bytes.NewBuffer(p.modelBytes)
-			cfg.LexicalShortlist = bytes.NewBuffer(p.shortlistBytes)
-			cfg.Vocabulary = bytes.NewBuffer(p.vocabularyBytes)
-
-			translator, err := New(ctx, cfg)
+			translator, err := p.newTranslator(ctx, i)
 			translators[i] = translator
 			return err
 		})
@@ -179,28 +566,150 @@ func (p *Pool) buildTranslators(ctx context.Context) ([]*Translator, error) {
 	return translators, err
 }
 
-func filesToBytes(p *Pool) error {
-	var err error
-	wrappingFile := new(alignedMemoryFile)
+// newTranslator creates a Translator for the worker using the files data shared between workers.
+func (p *Pool) newTranslator(ctx context.Context, workerID uint) (*Translator, error) {
+	if p.filesReleased {
+		return nil, ErrFilesReleased
+	}
+	cfg := p.cfg.Config
+	if cfg.Logger != nil {
+		cfg.Logger = cfg.Logger.With(slog.Uint64("worker_id", uint64(workerID)))
+	}
+	cfg.FilesBundle = p.files.filesBundle()
+	if p.pivotFiles != nil {
+		pivotFiles := p.pivotFiles.filesBundle()
+		cfg.Pivot = &pivotFiles
+	}
+	return New(ctx, cfg)
+}
 
-	wrappingFile.Reader = p.cfg.Model
+// isFatalError checks if the error means that state of the WASM module is undefined
+// and the Translator must not be used anymore.
+func isFatalError(err error) bool {
+	if errors.Is(err, ErrWorkerPanic) || errors.Is(err, ErrOutOfMemory) || errors.Is(err, wasm.ErrUnimplementedHostFunction) {
+		return true
+	}
+	// calls of closed or exited module
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		return true
+	}
+	// Go runtime panics in host functions (e.g. embind bindings) recovered by wazero
+	var runtimeErr runtime.Error
+	if errors.As(err, &runtimeErr) {
+		return true
+	}
+	return isWASMTrap(err)
+}
 
-	p.modelBytes, err = wrappingFile.readAll()
-	if err != nil {
-		return fmt.Errorf("failed to read model: %w", err)
+// wasmRuntimeErrorPkg is a package of wazero errors returned on WASM traps (unreachable instruction,
+// out of bounds memory access, stack overflow etc.). The package is internal, so its Error type is matched by name.
+// wazero has no public way to tell traps from other errors, so the match is pinned by TestIsWASMTrap.
+const wasmRuntimeErrorPkg = "github.com/tetratelabs/wazero/internal/wasmruntime"
+
+// isWASMTrap checks if the error chain contains wazero error of WASM trap.
+func isWASMTrap(err error) bool {
+	for err != nil {
+		if typ := reflect.TypeOf(err); typ.Kind() == reflect.Pointer &&
+			typ.Elem().PkgPath() == wasmRuntimeErrorPkg && typ.Elem().Name() == "Error" {
+			return true
+		}
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapped.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range wrapped.Unwrap() {
+				if isWASMTrap(err) {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
 	}
+	return false
+}
 
-	wrappingFile.Reader = p.cfg.LexicalShortlist
-	p.shortlistBytes, err = wrappingFile.readAll()
-	if err != nil {
-		return fmt.Errorf("failed to read shortlist: %w", err)
+// bundleBytes contains FilesBundle data shared between workers.
+// Data of the files missing in FilesBundle is nil.
+type bundleBytes struct {
+	model            []byte
+	shortlist        []byte
+	vocabulary       []byte
+	sourceVocabulary []byte
+	targetVocabulary []byte
+	qualityModel     []byte
+
+	// functions to unmap files mapped into memory
+	unmaps []func() error
+}
+
+// readBundleBytes reads data of the files. Files of *os.File type are mapped into memory if possible.
+func readBundleBytes(files FilesBundle) (bundleBytes, error) {
+	var b bundleBytes
+	for _, file := range [...]struct {
+		name   string
+		reader io.Reader
+		data   *[]byte
+	}{
+		{name: "model", reader: files.Model, data: &b.model},
+		{name: "shortlist", reader: files.LexicalShortlist, data: &b.shortlist},
+		{name: "vocabulary", reader: files.Vocabulary, data: &b.vocabulary},
+		{name: "source vocabulary", reader: files.SourceVocabulary, data: &b.sourceVocabulary},
+		{name: "target vocabulary", reader: files.TargetVocabulary, data: &b.targetVocabulary},
+		{name: "quality model", reader: files.QualityModel, data: &b.qualityModel},
+	} {
+		if file.reader == nil {
+			continue
+		}
+		if f, ok := file.reader.(*os.File); ok {
+			// falling back to reading if the file can't be mapped, e.g. if it is a pipe
+			if data, unmap, err := mmapFile(f); err == nil {
+				*file.data = data
+				b.unmaps = append(b.unmaps, unmap)
+				continue
+			}
+		}
+		wrappingFile := &alignedMemoryFile{Reader: file.reader}
+		data, err := wrappingFile.readAll()
+		if err != nil {
+			return bundleBytes{}, errors.Join(fmt.Errorf("failed to read %s: %w", file.name, err), b.release())
+		}
+		if data == nil {
+			// distinguishing empty files from missing ones
+			data = []byte{}
+		}
+		*file.data = data
 	}
+	return b, nil
+}
 
-	wrappingFile.Reader = p.cfg.Vocabulary
-	p.vocabularyBytes, err = wrappingFile.readAll()
-	if err != nil {
-		return fmt.Errorf("failed to read vocabulary: %w", err)
+// release unmaps the mapped files. The data must not be used after that.
+func (b bundleBytes) release() error {
+	var err error
+	for _, unmap := range b.unmaps {
+		err = errors.Join(err, unmap())
+	}
+	return err
+}
+
+// filesBundle creates a FilesBundle with readers over the shared data.
+func (b bundleBytes) filesBundle() FilesBundle {
+	return FilesBundle{
+		Model:            bytesReader(b.model),
+		LexicalShortlist: bytesReader(b.shortlist),
+		Vocabulary:       bytesReader(b.vocabulary),
+		SourceVocabulary: bytesReader(b.sourceVocabulary),
+		TargetVocabulary: bytesReader(b.targetVocabulary),
+		QualityModel:     bytesReader(b.qualityModel),
 	}
+}
 
-	return nil
+// bytesReader returns nil reader for nil data.
+func bytesReader(data []byte) io.Reader {
+	if data == nil {
+		return nil
+	}
+	return bytes.NewBuffer(data)
 }
@@ -0,0 +1,340 @@
+package gobergamot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+
+	"github.com/KSpaceer/gobergamot/internal/errgroup"
+	"github.com/KSpaceer/gobergamot/internal/wasm"
+)
+
+// newTestPool creates a pool without workers and files.
+func newTestPool(cfg PoolConfig) *Pool {
+	cfg.Observer = observerOrNop(cfg.Observer)
+	return &Pool{
+		cfg:   cfg,
+		queue: newRequestQueue(cfg.MaxQueueDepth),
+		done:  make(chan struct{}),
+		eg:    errgroup.New(),
+	}
+}
+
+func TestPool_FilesReleasedWithoutWorkers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var failures []error
+	p := newTestPool(PoolConfig{
+		MinSize:      1,
+		MaxSize:      1,
+		ReleaseFiles: true,
+		OnWorkerFailure: func(_ uint, err error) {
+			failures = append(failures, err)
+		},
+	})
+	p.filesReleased = true
+	p.workers = 1
+
+	// the last worker fails to recover, so the queued request must fail
+	queued := &workerRequest{ctx: ctx, respChan: make(chan workerResponse, 1)}
+	if _, err := p.queue.push(queued, PriorityNormal); err != nil {
+		t.Fatalf("failed to push request: %v", err)
+	}
+	if translator := p.recoverWorker(0); translator != nil {
+		t.Fatalf("expected worker not to be recovered")
+	}
+	if len(failures) != 1 || !errors.Is(failures[0], ErrFilesReleased) {
+		t.Errorf("expected failure with ErrFilesReleased, got %v", failures)
+	}
+	if resp := <-queued.respChan; !errors.Is(resp.err, ErrFilesReleased) {
+		t.Errorf("expected queued request to fail with ErrFilesReleased, got %v", resp.err)
+	}
+
+	// and so must the requests arriving later
+	_, err := p.Translate(ctx, TranslationRequest{Text: "Hello World"})
+	if !errors.Is(err, ErrFilesReleased) {
+		t.Errorf("expected ErrFilesReleased, got %v", err)
+	}
+	if size := p.Size(); size != 0 {
+		t.Errorf("expected pool without workers, got %d", size)
+	}
+}
+
+func TestPool_CloseReleasesFiles(t *testing.T) {
+	p := newTestPool(PoolConfig{MinSize: 1, MaxSize: 1})
+	released := make(chan struct{})
+	p.files.unmaps = append(p.files.unmaps, func() error {
+		close(released)
+		return nil
+	})
+	stop := make(chan struct{})
+	p.eg.Go(func() error {
+		// worker finishing its translation after Close has stopped waiting
+		<-stop
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Close(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	select {
+	case <-released:
+		t.Fatalf("files are released while a worker is running")
+	default:
+	}
+
+	close(stop)
+	select {
+	case <-released:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("files are not released after workers are stopped")
+	}
+}
+
+// trapModule is a WASM module exporting functions causing traps: "trap" executes unreachable instruction,
+// "out_of_bounds" loads from empty memory and "divide_by_zero" divides integer by zero.
+var trapModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic and version
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: func() with no params and results
+	0x03, 0x04, 0x03, 0x00, 0x00, 0x00, // function section: three functions of type 0
+	0x05, 0x03, 0x01, 0x00, 0x00, // memory section: memory of 0 pages
+	0x07, 0x29, 0x03, // export section: 3 exports
+	0x04, 't', 'r', 'a', 'p', 0x00, 0x00, // "trap" function 0
+	0x0d, 'o', 'u', 't', '_', 'o', 'f', '_', 'b', 'o', 'u', 'n', 'd', 's', 0x00, 0x01, // "out_of_bounds" function 1
+	0x0e, 'd', 'i', 'v', 'i', 'd', 'e', '_', 'b', 'y', '_', 'z', 'e', 'r', 'o', 0x00, 0x02, // "divide_by_zero" function 2
+	0x0a, 0x17, 0x03, // code section: 3 functions
+	0x03, 0x00, 0x00, 0x0b, // no locals, unreachable, end
+	0x08, 0x00, 0x41, 0x00, 0x28, 0x02, 0x00, 0x1a, 0x0b, // no locals, i32.load of address 0, drop, end
+	0x08, 0x00, 0x41, 0x01, 0x41, 0x00, 0x6d, 0x1a, 0x0b, // no locals, i32.div_s of 1 by 0, drop, end
+}
+
+// hostCallerModule returns a WASM module exporting "call" function, which calls imported host.name function.
+func hostCallerModule(name string) []byte {
+	module := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic and version
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: func() with no params and results
+	}
+	// import section: function host.name of type 0
+	imports := append([]byte{0x01, 0x04, 'h', 'o', 's', 't', byte(len(name))}, name...)
+	imports = append(imports, 0x00, 0x00)
+	module = append(module, 0x02, byte(len(imports)))
+	module = append(module, imports...)
+	return append(module,
+		0x03, 0x02, 0x01, 0x00, // function section: one function of type 0
+		0x07, 0x08, 0x01, 0x04, 'c', 'a', 'l', 'l', 0x00, 0x01, // export section: "call" function 1
+		0x0a, 0x06, 0x01, 0x04, 0x00, 0x10, 0x00, 0x0b, // code section: no locals, call 0, end
+	)
+}
+
+func TestIsFatalError(t *testing.T) {
+	ctx := context.Background()
+	wasmRuntime := wazero.NewRuntime(ctx)
+	defer wasmRuntime.Close(ctx)
+
+	trapMod, err := wasmRuntime.Instantiate(ctx, trapModule)
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	_, trapErr := trapMod.ExportedFunction("trap").Call(ctx)
+
+	_, err = wasmRuntime.NewHostModuleBuilder("host").
+		NewFunctionBuilder().WithFunc(func(context.Context) {
+		var m map[string]int
+		m["nil map"]++
+	}).Export("nil_map").
+		NewFunctionBuilder().WithFunc(func(context.Context) {
+		panic(wasm.ErrUnimplementedHostFunction)
+	}).Export("unimplemented").
+		NewFunctionBuilder().WithFunc(func(context.Context) {
+		panic(ErrOutOfMemory)
+	}).Export("out_of_memory").
+		Instantiate(ctx)
+	if err != nil {
+		t.Fatalf("failed to instantiate host module: %v", err)
+	}
+	// host functions recover panics only if they are called by WASM code
+	callHost := func(name string) error {
+		mod, err := wasmRuntime.InstantiateWithConfig(ctx, hostCallerModule(name), wazero.NewModuleConfig().WithName(name))
+		if err != nil {
+			t.Fatalf("failed to instantiate module: %v", err)
+		}
+		_, err = mod.ExportedFunction("call").Call(ctx)
+		return err
+	}
+	runtimeErr := callHost("nil_map")
+	unimplementedErr := callHost("unimplemented")
+	if !errors.Is(unimplementedErr, wasm.ErrUnimplementedHostFunction) {
+		t.Errorf("expected panic value of host function to be kept, got %v", unimplementedErr)
+	}
+	outOfMemoryErr := callHost("out_of_memory")
+	if !errors.Is(outOfMemoryErr, ErrOutOfMemory) {
+		t.Errorf("expected panic value of host function to match ErrOutOfMemory, got %v", outOfMemoryErr)
+	}
+
+	closedMod, err := wasmRuntime.InstantiateWithConfig(ctx, trapModule, wazero.NewModuleConfig().WithName("closed"))
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	if err := closedMod.Close(ctx); err != nil {
+		t.Fatalf("failed to close module: %v", err)
+	}
+	_, closedErr := closedMod.ExportedFunction("trap").Call(ctx)
+
+	tests := []struct {
+		name  string
+		err   error
+		fatal bool
+	}{
+		{name: "wasm trap", err: trapErr, fatal: true},
+		{name: "wrapped wasm trap", err: fmt.Errorf("failed to translate: %w", trapErr), fatal: true},
+		{name: "joined wasm trap", err: errors.Join(errors.New("cleanup"), trapErr), fatal: true},
+		{name: "host function runtime panic", err: runtimeErr, fatal: true},
+		{name: "unimplemented host function", err: unimplementedErr, fatal: true},
+		{name: "closed module", err: closedErr, fatal: true},
+		{name: "worker panic", err: fmt.Errorf("%w: boom", ErrWorkerPanic), fatal: true},
+		{name: "out of memory", err: outOfMemoryErr, fatal: true},
+		{name: "trap-like message", err: errors.New("unreachable\nwasm stack trace:"), fatal: false},
+		{name: "context canceled", err: context.Canceled, fatal: false},
+		{name: "input too large", err: ErrInputTooLarge, fatal: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.err == nil {
+				t.Fatalf("expected an error")
+			}
+			if fatal := isFatalError(tc.err); fatal != tc.fatal {
+				t.Errorf("expected isFatalError(%v) = %v, got %v", tc.err, tc.fatal, fatal)
+			}
+		})
+	}
+}
+
+// TestIsWASMTrap pins the type of wazero trap errors, which is matched by package path
+// because wazero doesn't export it.
+func TestIsWASMTrap(t *testing.T) {
+	ctx := context.Background()
+	wasmRuntime := wazero.NewRuntime(ctx)
+	defer wasmRuntime.Close(ctx)
+
+	mod, err := wasmRuntime.Instantiate(ctx, trapModule)
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	for _, name := range []string{"trap", "out_of_bounds", "divide_by_zero"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := mod.ExportedFunction(name).Call(ctx)
+			if err == nil {
+				t.Fatalf("expected a trap")
+			}
+			if !isWASMTrap(err) {
+				t.Errorf("trap error %v (%T) is not matched, wazero errors are not in %s anymore",
+					err, errors.Unwrap(err), wasmRuntimeErrorPkg)
+			}
+		})
+	}
+}
+
+func TestHandleBatch_FatalError(t *testing.T) {
+	ctx := context.Background()
+	newRequest := func() *workerRequest {
+		return &workerRequest{
+			ctx:      ctx,
+			reqs:     []TranslationRequest{{Text: "Hello World"}},
+			respChan: make(chan workerResponse, 1),
+		}
+	}
+
+	// nil translator panics, which is a fatal error
+	batch := []*workerRequest{newRequest(), newRequest()}
+	retry, err := handleBatch(0, nil, batch)
+	if !errors.Is(err, ErrWorkerPanic) {
+		t.Fatalf("expected ErrWorkerPanic, got %v", err)
+	}
+	if len(retry) != len(batch) {
+		t.Fatalf("expected %d requests to retry, got %d", len(batch), len(retry))
+	}
+	for _, req := range batch {
+		select {
+		case resp := <-req.respChan:
+			t.Errorf("unexpected response to the request to retry: %v", resp.err)
+		default:
+		}
+	}
+
+	// single request fails on its own
+	req := newRequest()
+	retry, err = handleBatch(0, nil, []*workerRequest{req})
+	if !errors.Is(err, ErrWorkerPanic) || len(retry) != 0 {
+		t.Fatalf("expected ErrWorkerPanic without requests to retry, got %v, %d", err, len(retry))
+	}
+	if resp := <-req.respChan; !errors.Is(resp.err, ErrWorkerPanic) {
+		t.Errorf("expected response with ErrWorkerPanic, got %v", resp.err)
+	}
+}
+
+func TestPool_CollectingWorkerIsAvailable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	p := newTestPool(PoolConfig{
+		MaxSize:       4,
+		BatchWindow:   time.Second,
+		BatchMaxWords: DefaultBatchMaxWords,
+	})
+	p.workers = 1
+
+	first := &workerRequest{ctx: ctx, reqs: []TranslationRequest{{Text: "Hello"}}, respChan: make(chan workerResponse, 1)}
+	batchCh := make(chan []*workerRequest, 1)
+	go func() {
+		batchCh <- p.collectBatch(first)
+	}()
+	for p.collectingWorkers.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// requests arriving during the batch window are taken by the collecting worker
+	const arriving = 3
+	errs := make(chan error, arriving)
+	for i := 0; i < arriving; i++ {
+		go func() {
+			resp := p.process(ctx, []TranslationRequest{{Text: "World"}}, false)
+			errs <- resp.err
+		}()
+		// waiting for the request to be taken
+		time.Sleep(10 * time.Millisecond)
+		for p.QueueLen() != 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	var batch []*workerRequest
+	select {
+	case batch = <-batchCh:
+	case <-ctx.Done():
+		t.Fatalf("batch is not collected")
+	}
+	for _, req := range batch[1:] {
+		req.respChan <- workerResponse{outputs: []string{"Мир"}}
+	}
+	for i := 0; i < arriving; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	p.mu.Lock()
+	spawned := p.nextID
+	p.mu.Unlock()
+	if spawned != 0 {
+		t.Errorf("expected no workers spawned for requests taken by collecting worker, got %d", spawned)
+	}
+	if len(batch) != arriving+1 {
+		t.Errorf("expected %d requests in the batch, got %d", arriving+1, len(batch))
+	}
+}
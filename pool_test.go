@@ -3,9 +3,14 @@ package gobergamot_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/tetratelabs/wazero/sys"
+
 	"github.com/KSpaceer/gobergamot"
 	"github.com/KSpaceer/gobergamot/internal/wasm"
 )
@@ -91,3 +96,303 @@ func TestPool(t *testing.T) {
 		}
 	}
 }
+
+func TestPoolConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     gobergamot.PoolConfig
+		wantErr bool
+	}{
+		{
+			name: "fixed size",
+			cfg:  gobergamot.PoolConfig{PoolSize: 2},
+		},
+		{
+			name: "elastic",
+			cfg:  gobergamot.PoolConfig{MinSize: 1, MaxSize: 4, IdleTimeout: time.Second},
+		},
+		{
+			name: "elastic without workers at start",
+			cfg:  gobergamot.PoolConfig{MaxSize: 4},
+		},
+		{
+			name:    "zero size",
+			cfg:     gobergamot.PoolConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "min size without max size",
+			cfg:     gobergamot.PoolConfig{MinSize: 2},
+			wantErr: true,
+		},
+		{
+			name:    "pool size with max size",
+			cfg:     gobergamot.PoolConfig{PoolSize: 2, MaxSize: 4},
+			wantErr: true,
+		},
+		{
+			name:    "min size greater than max size",
+			cfg:     gobergamot.PoolConfig{MinSize: 5, MaxSize: 4},
+			wantErr: true,
+		},
+		{
+			name:    "negative batch window",
+			cfg:     gobergamot.PoolConfig{PoolSize: 1, BatchWindow: -time.Second},
+			wantErr: true,
+		},
+		{
+			name:    "negative idle timeout",
+			cfg:     gobergamot.PoolConfig{MaxSize: 4, IdleTimeout: -time.Second},
+			wantErr: true,
+		},
+		{
+			name: "fixed size with released files",
+			cfg:  gobergamot.PoolConfig{PoolSize: 2, ReleaseFiles: true},
+		},
+		{
+			name:    "elastic with released files",
+			cfg:     gobergamot.PoolConfig{MaxSize: 4, ReleaseFiles: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.FilesBundle = gobergamot.FilesBundle{
+				Model:            bytes.NewBuffer(nil),
+				LexicalShortlist: bytes.NewBuffer(nil),
+				Vocabulary:       bytes.NewBuffer(nil),
+			}
+			err := tc.cfg.Validate()
+			if tc.wantErr && err == nil {
+				t.Errorf("expected error")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestPool_Elastic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	pool, err := gobergamot.NewPool(ctx, gobergamot.PoolConfig{
+		Config: gobergamot.Config{
+			CompileConfig: wasm.CompileConfig{
+				Stderr: stderr,
+				Stdout: stdout,
+			},
+			FilesBundle: testBundle(t),
+		},
+		MinSize:     0,
+		MaxSize:     2,
+		IdleTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewPool returned error %v", err)
+	}
+	t.Cleanup(func() {
+		if err := pool.Close(ctx); err != nil {
+			t.Fatalf("failed to close pool: %v", err)
+		}
+	})
+
+	if size := pool.Size(); size != 0 {
+		t.Fatalf("expected pool to start without workers, got %d", size)
+	}
+
+	output, err := pool.Translate(ctx, gobergamot.TranslationRequest{Text: "Hello World"})
+	if err != nil {
+		t.Fatalf("failed to translate: %v", err)
+	}
+	if output != helloWorldTranslation {
+		t.Errorf("unexpected output %s", output)
+	}
+	if size := pool.Size(); size == 0 {
+		t.Errorf("expected pool to spawn a worker")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for pool.Size() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected idle workers to be closed, got %d workers", pool.Size())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPool_SpawnFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+
+	var failures atomic.Int64
+	// pool without workers at start failing to spawn them
+	pool, err := gobergamot.NewPool(ctx, gobergamot.PoolConfig{
+		Config: gobergamot.Config{
+			FilesBundle: gobergamot.FilesBundle{
+				Model:            bytes.NewBuffer([]byte{}),
+				LexicalShortlist: bytes.NewBuffer([]byte{}),
+				Vocabulary:       bytes.NewBuffer([]byte{}),
+			},
+		},
+		MaxSize: 1,
+		OnWorkerFailure: func(uint, error) {
+			failures.Add(1)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPool returned error %v", err)
+	}
+	t.Cleanup(func() {
+		if err := pool.Close(ctx); err != nil {
+			t.Fatalf("failed to close pool: %v", err)
+		}
+	})
+
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := pool.Translate(ctx, gobergamot.TranslationRequest{Text: "Hello World"})
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		// queued requests must not wait for a worker which is never going to be created
+		if err := <-errs; err == nil || ctx.Err() != nil {
+			t.Fatalf("expected spawn error, got %v", err)
+		}
+	}
+	if failures.Load() == 0 {
+		t.Errorf("expected OnWorkerFailure to be called")
+	}
+	if size := pool.Size(); size != 0 {
+		t.Errorf("expected pool without workers, got %d", size)
+	}
+	if length := pool.QueueLen(); length != 0 {
+		t.Errorf("expected empty queue, got %d requests", length)
+	}
+}
+
+func TestPool_WorkerRecovery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+
+	failures := make(chan error, 10)
+	pool, err := gobergamot.NewPool(ctx, gobergamot.PoolConfig{
+		Config: gobergamot.Config{
+			FilesBundle: testBundle(t),
+			// module is closed if the context is done during translation, which breaks the worker
+			WASMUseContext: true,
+		},
+		PoolSize: 1,
+		OnWorkerFailure: func(_ uint, err error) {
+			failures <- err
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPool returned error %v", err)
+	}
+	t.Cleanup(func() {
+		if err := pool.Close(ctx); err != nil {
+			t.Fatalf("failed to close pool: %v", err)
+		}
+	})
+
+	breakCtx, breakCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer breakCancel()
+	text := strings.Repeat("Computers have opened up new possibilities. ", 500)
+	if _, err := pool.Translate(breakCtx, gobergamot.TranslationRequest{Text: text}); err == nil {
+		t.Fatalf("expected translation to be interrupted")
+	}
+	select {
+	case err := <-failures:
+		var exitErr *sys.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Errorf("expected failure with sys.ExitError, got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("OnWorkerFailure is not called")
+	}
+
+	// the only worker must be replaced with a new one
+	output, err := pool.Translate(ctx, gobergamot.TranslationRequest{Text: "Hello World"})
+	if err != nil {
+		t.Fatalf("failed to translate after worker failure: %v", err)
+	}
+	if output != helloWorldTranslation {
+		t.Errorf("unexpected output %s", output)
+	}
+	if size := pool.Size(); size != 1 {
+		t.Errorf("expected 1 worker, got %d", size)
+	}
+	select {
+	case err := <-failures:
+		t.Errorf("unexpected worker failure: %v", err)
+	default:
+	}
+}
+
+func TestPool_Batching(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	pool, err := gobergamot.NewPool(ctx, gobergamot.PoolConfig{
+		Config: gobergamot.Config{
+			CompileConfig: wasm.CompileConfig{
+				Stderr: stderr,
+				Stdout: stdout,
+			},
+			FilesBundle: testBundle(t),
+		},
+		PoolSize:      1,
+		BatchWindow:   50 * time.Millisecond,
+		BatchMaxWords: 6,
+	})
+	if err != nil {
+		t.Fatalf("NewPool returned error %v", err)
+	}
+	t.Cleanup(func() {
+		if err := pool.Close(ctx); err != nil {
+			t.Fatalf("failed to close pool: %v", err)
+		}
+	})
+
+	type result struct {
+		text     string
+		expected string
+		err      error
+	}
+	results := make(chan result)
+	for i := 0; i < 5; i++ {
+		go func() {
+			output, err := pool.Translate(ctx, gobergamot.TranslationRequest{Text: "Hello World"})
+			results <- result{text: output, expected: helloWorldTranslation, err: err}
+		}()
+		go func() {
+			outputs, err := pool.TranslateMultiple(
+				ctx,
+				gobergamot.TranslationRequest{Text: "Goodbye World"},
+				gobergamot.TranslationRequest{Text: "Hello World"},
+			)
+			if err == nil && len(outputs) != 2 {
+				t.Errorf("expected 2 outputs, got %d", len(outputs))
+			}
+			results <- result{text: strings.Join(outputs, "|"), expected: goodbyeWorldTranslation + "|" + helloWorldTranslation, err: err}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		res := <-results
+		if res.err != nil {
+			t.Errorf("failed to translate: %v", res.err)
+		} else if res.text != res.expected {
+			t.Errorf("expected %q, got %q", res.expected, res.text)
+		}
+	}
+}
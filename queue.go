@@ -0,0 +1,162 @@
+package gobergamot
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("pool queue is full")
+
+// Priority defines an order of processing requests waiting for a free worker in Pool.
+// Requests with higher priority are processed first, requests with the same priority
+// are processed in order of arrival.
+type Priority int
+
+const (
+	// PriorityBackground is for batch jobs which can wait for interactive requests.
+	PriorityBackground Priority = iota - 1
+	// PriorityNormal is a default priority.
+	PriorityNormal
+	// PriorityInteractive is for requests someone is waiting for, e.g. from UI.
+	PriorityInteractive
+)
+
+// priorityLevels is a number of Priority values
+const priorityLevels = int(PriorityInteractive-PriorityBackground) + 1
+
+type priorityKey struct{}
+
+// WithPriority returns a context making Pool process the request with given priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns priority set with WithPriority or PriorityNormal if it is not set.
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// clamp converts unknown priorities into the nearest known one.
+func (p Priority) clamp() Priority {
+	return max(PriorityBackground, min(p, PriorityInteractive))
+}
+
+// requestQueue is a FIFO queue of requests for every priority.
+type requestQueue struct {
+	mu sync.Mutex
+	// queues by priority level, from the lowest priority to the highest
+	levels   [priorityLevels]list.List
+	len      uint
+	maxDepth uint
+
+	// ready contains a token while the queue is not empty and there is no worker taking a request.
+	// Worker taking a request puts the token back if there are requests left in the queue.
+	ready chan struct{}
+}
+
+func newRequestQueue(maxDepth uint) *requestQueue {
+	return &requestQueue{
+		maxDepth: maxDepth,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push puts the request into the queue and returns length of the queue.
+// Zero maxDepth means that the queue is unbounded.
+func (q *requestQueue) push(req *workerRequest, priority Priority) (uint, error) {
+	q.mu.Lock()
+	if q.maxDepth != 0 && q.len >= q.maxDepth {
+		q.mu.Unlock()
+		return 0, ErrQueueFull
+	}
+	level := &q.levels[priority.clamp()-PriorityBackground]
+	req.elem = level.PushBack(req)
+	req.level = level
+	q.len++
+	length := q.len
+	q.mu.Unlock()
+
+	q.signal()
+	return length, nil
+}
+
+// pop takes the earliest request with the highest priority. Returns false if the queue is empty.
+func (q *requestQueue) pop() (*workerRequest, bool) {
+	req, _ := q.popIf(func(*workerRequest) bool { return true })
+	return req, req != nil
+}
+
+// popIf takes the earliest request with the highest priority if it matches.
+// Returns nil request if the queue is empty or the request does not match.
+func (q *requestQueue) popIf(match func(*workerRequest) bool) (req *workerRequest, empty bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := len(q.levels) - 1; i >= 0; i-- {
+		front := q.levels[i].Front()
+		if front == nil {
+			continue
+		}
+		if req := front.Value.(*workerRequest); !match(req) {
+			// the request is left for other workers
+			q.signal()
+			return nil, false
+		}
+		req := q.levels[i].Remove(front).(*workerRequest)
+		req.elem, req.level = nil, nil
+		req.takenAt = time.Now()
+		q.len--
+		if q.len > 0 {
+			q.signal()
+		}
+		return req, false
+	}
+	return nil, true
+}
+
+// remove deletes the request from the queue. Returns false if the request has been already taken.
+func (q *requestQueue) remove(req *workerRequest) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if req.elem == nil {
+		return false
+	}
+	req.level.Remove(req.elem)
+	req.elem, req.level = nil, nil
+	q.len--
+	return true
+}
+
+// drain takes all the requests from the queue.
+func (q *requestQueue) drain() []*workerRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	reqs := make([]*workerRequest, 0, q.len)
+	for i := len(q.levels) - 1; i >= 0; i-- {
+		for q.levels[i].Len() > 0 {
+			req := q.levels[i].Remove(q.levels[i].Front()).(*workerRequest)
+			req.elem, req.level = nil, nil
+			reqs = append(reqs, req)
+		}
+	}
+	q.len = 0
+	return reqs
+}
+
+func (q *requestQueue) length() uint {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.len
+}
+
+func (q *requestQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,76 @@
+package gobergamot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRequestQueue(t *testing.T) {
+	ctx := context.Background()
+	queue := newRequestQueue(3)
+
+	background := &workerRequest{ctx: ctx}
+	normal := &workerRequest{ctx: ctx}
+	interactive := &workerRequest{ctx: ctx}
+	for _, item := range []struct {
+		req      *workerRequest
+		priority Priority
+	}{
+		{req: background, priority: PriorityBackground},
+		{req: normal, priority: PriorityNormal},
+		{req: interactive, priority: PriorityInteractive},
+	} {
+		if _, err := queue.push(item.req, item.priority); err != nil {
+			t.Fatalf("failed to push request: %v", err)
+		}
+	}
+	if _, err := queue.push(&workerRequest{ctx: ctx}, PriorityInteractive); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	// cancelled request must leave the queue
+	if !queue.remove(normal) {
+		t.Fatalf("expected queued request to be removed")
+	}
+	if queue.remove(normal) {
+		t.Errorf("expected request to be removed only once")
+	}
+	if length := queue.length(); length != 2 {
+		t.Errorf("expected 2 queued requests, got %d", length)
+	}
+
+	for _, want := range []*workerRequest{interactive, background} {
+		req, ok := queue.pop()
+		if !ok {
+			t.Fatalf("expected queued request")
+		}
+		if req != want {
+			t.Errorf("requests are taken out of priority order")
+		}
+		if queue.remove(req) {
+			t.Errorf("expected taken request not to be removed")
+		}
+	}
+	if _, ok := queue.pop(); ok {
+		t.Errorf("expected empty queue")
+	}
+}
+
+func TestRequestQueue_Drain(t *testing.T) {
+	queue := newRequestQueue(0)
+	first, second := &workerRequest{}, &workerRequest{}
+	_, _ = queue.push(first, PriorityBackground)
+	_, _ = queue.push(second, PriorityInteractive)
+
+	reqs := queue.drain()
+	if len(reqs) != 2 || reqs[0] != second || reqs[1] != first {
+		t.Errorf("expected requests in priority order, got %v", reqs)
+	}
+	if length := queue.length(); length != 0 {
+		t.Errorf("expected empty queue, got %d requests", length)
+	}
+	if queue.remove(first) {
+		t.Errorf("expected drained request not to be removed")
+	}
+}
@@ -0,0 +1,260 @@
+package gobergamot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KSpaceer/gobergamot/internal/gen"
+)
+
+// DefaultPivotLanguage is a language used by Registry to translate between languages
+// having no direct model.
+const DefaultPivotLanguage = "en"
+
+var (
+	ErrNoModels                = errors.New("at least one model is required")
+	ErrUnsupportedLanguagePair = errors.New("unsupported language pair")
+)
+
+// LanguagePair represents a direction of translation.
+type LanguagePair struct {
+	// Code of source language, e.g. "es"
+	From string
+	// Code of target language, e.g. "en"
+	To string
+}
+
+func (p LanguagePair) String() string {
+	return p.From + "-" + p.To
+}
+
+type RegistryConfig struct {
+	// Config contains options of WASM module and translation models.
+	// Config.FilesBundle and Config.Pivot are not used by Registry.
+	Config
+
+	// Data of the models to load, keyed by language pair
+	Models map[LanguagePair]FilesBundle
+
+	// PivotLanguage is a language to translate via if there is no direct model for requested language pair.
+	// Defaults to DefaultPivotLanguage.
+	PivotLanguage string
+}
+
+func (cfg RegistryConfig) Validate() error {
+	err := cfg.Config.validateLimits()
+	if len(cfg.Models) == 0 {
+		return errors.Join(err, ErrNoModels)
+	}
+	for pair, files := range cfg.Models {
+		if filesErr := files.Validate(); filesErr != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", pair, filesErr))
+		}
+	}
+	return err
+}
+
+// Registry holds translation models for multiple language pairs in a single Bergamot module
+// and routes translation requests to them by TranslationRequest From and To fields.
+// If there is no direct model for a language pair, Registry translates via the pivot language.
+//
+// Like Translator, Registry is not safe for concurrent use.
+type Registry struct {
+	translator *Translator
+	models     map[LanguagePair]*gen.ClassTranslationModel
+	pivot      string
+}
+
+// NewRegistry compiles Bergamot module and loads all models from config into it.
+func NewRegistry(ctx context.Context, cfg RegistryConfig) (*Registry, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.PivotLanguage == "" {
+		cfg.PivotLanguage = DefaultPivotLanguage
+	}
+
+	start := time.Now()
+	translator, err := newTranslator(ctx, cfg.Config)
+	if err != nil {
+		observerOrNop(cfg.Observer).ObserveNew(ctx, TranslatorEvent{Start: start, Duration: time.Since(start), Err: err})
+		return nil, err
+	}
+	r := &Registry{
+		translator: translator,
+		models:     make(map[LanguagePair]*gen.ClassTranslationModel, len(cfg.Models)),
+		pivot:      cfg.PivotLanguage,
+	}
+	for pair, files := range cfg.Models {
+		r.models[pair], err = translator.loadModel(ctx, files)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", pair, err)
+			break
+		}
+	}
+	translator.cfg.Observer.ObserveNew(ctx, TranslatorEvent{
+		Start:        start,
+		Duration:     time.Since(start),
+		TranslatorID: translator.id,
+		MemoryBytes:  translator.memoryBytes(),
+		Err:          err,
+	})
+	if err != nil {
+		// the registry is not returned, so nothing else can close the runtime
+		_ = translator.wasmRuntime.Close(ctx)
+		return nil, err
+	}
+	return r, nil
+}
+
+// LanguagePairs returns language pairs having direct models in the Registry.
+func (r *Registry) LanguagePairs() []LanguagePair {
+	pairs := make([]LanguagePair, 0, len(r.models))
+	for pair := range r.models {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].From != pairs[j].From {
+			return pairs[i].From < pairs[j].From
+		}
+		return pairs[i].To < pairs[j].To
+	})
+	return pairs
+}
+
+// Supports checks if the Registry can translate from one language to another directly or via pivoting.
+func (r *Registry) Supports(from, to string) bool {
+	_, _, err := r.route(LanguagePair{From: from, To: to})
+	return err == nil
+}
+
+// Translate translates text provided in the request from request From language into request To language.
+func (r *Registry) Translate(ctx context.Context, request TranslationRequest) (string, error) {
+	translatedTexts, err := r.TranslateMultiple(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if len(translatedTexts) < 1 {
+		return "", fmt.Errorf("expected translated texts to have at least 1 element")
+	}
+	return translatedTexts[0], nil
+}
+
+// TranslateMultiple translates a batch of text provided in the requests. Requests may have different
+// language pairs, in this case requests are translated with a single call for every language pair.
+func (r *Registry) TranslateMultiple(ctx context.Context, requests ...TranslationRequest) ([]string, error) {
+	return translateProtected(requests, r.translator.cfg.Glossary, func(requests []TranslationRequest) ([]string, error) {
+		return translateRouted(ctx, r, requests, false, func([]TranslationRequest) responseReader[string] {
+			return readTranslatedText
+		})
+	}, translatedText, protectedText)
+}
+
+// TranslateDetailed is similar to Translate, but also returns information about sentences
+// of the original and translated texts.
+func (r *Registry) TranslateDetailed(ctx context.Context, request TranslationRequest) (TranslationResult, error) {
+	results, err := r.TranslateMultipleDetailed(ctx, request)
+	if err != nil {
+		return TranslationResult{}, err
+	}
+	if len(results) < 1 {
+		return TranslationResult{}, fmt.Errorf("expected translation results to have at least 1 element")
+	}
+	return results[0], nil
+}
+
+// TranslateMultipleDetailed is similar to TranslateMultiple, but also returns information about sentences
+// of the original and translated texts.
+func (r *Registry) TranslateMultipleDetailed(
+	ctx context.Context,
+	requests ...TranslationRequest,
+) ([]TranslationResult, error) {
+	return translateProtected(requests, r.translator.cfg.Glossary, func(requests []TranslationRequest) ([]TranslationResult, error) {
+		return translateRouted(ctx, r, requests, true, func([]TranslationRequest) responseReader[TranslationResult] {
+			return readTranslationResult
+		})
+	}, resultText, protectedResult)
+}
+
+// Close deletes loaded models and stops the WASM runtime
+func (r *Registry) Close(ctx context.Context) error {
+	var err error
+	for pair, model := range r.models {
+		if deleteErr := model.Delete(ctx); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to delete %s model: %w", pair, deleteErr))
+		}
+	}
+	return errors.Join(err, r.translator.Close(ctx))
+}
+
+// route finds models to translate texts for given language pair.
+// If pivot model is not nil, texts must be translated via pivoting.
+func (r *Registry) route(pair LanguagePair) (model, pivotModel *gen.ClassTranslationModel, err error) {
+	if model, ok := r.models[pair]; ok {
+		return model, nil, nil
+	}
+	if pair.From != r.pivot && pair.To != r.pivot {
+		model, ok := r.models[LanguagePair{From: pair.From, To: r.pivot}]
+		pivotModel, pivotOk := r.models[LanguagePair{From: r.pivot, To: pair.To}]
+		if ok && pivotOk {
+			return model, pivotModel, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguagePair, pair)
+}
+
+// translateRouted groups requests by language pair, translates every group with corresponding models
+// and returns results in the order of requests.
+func translateRouted[T any](
+	ctx context.Context,
+	r *Registry,
+	requests []TranslationRequest,
+	detailed bool,
+	newReader func(requests []TranslationRequest) responseReader[T],
+) ([]T, error) {
+	var (
+		pairs  []LanguagePair
+		groups = make(map[LanguagePair][]int)
+	)
+	for i := range requests {
+		pair := LanguagePair{From: requests[i].From, To: requests[i].To}
+		if _, ok := groups[pair]; !ok {
+			pairs = append(pairs, pair)
+		}
+		groups[pair] = append(groups[pair], i)
+	}
+
+	output := make([]T, len(requests))
+	for _, pair := range pairs {
+		model, pivotModel, err := r.route(pair)
+		if err != nil {
+			return nil, err
+		}
+		indices := groups[pair]
+		groupRequests := make([]TranslationRequest, len(indices))
+		for i, idx := range indices {
+			groupRequests[i] = requests[idx]
+		}
+		start := time.Now()
+		resp, err := r.translator.translateWith(ctx, model, pivotModel, groupRequests)
+		if err != nil {
+			r.translator.observeTranslation(ctx, start, groupRequests, detailed, err)
+			return nil, fmt.Errorf("%s: %w", pair, err)
+		}
+		results, err := processResponse(ctx, resp, newReader(groupRequests))
+		r.translator.observeTranslation(ctx, start, groupRequests, detailed, err)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pair, err)
+		}
+		if len(results) != len(indices) {
+			return nil, fmt.Errorf("%s: expected %d translation results, got %d", pair, len(indices), len(results))
+		}
+		for i, idx := range indices {
+			output[idx] = results[i]
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,85 @@
+package gobergamot_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/internal/wasm"
+)
+
+func TestNewRegistry_Validation(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := gobergamot.NewRegistry(ctx, gobergamot.RegistryConfig{})
+	if !errors.Is(err, gobergamot.ErrNoModels) {
+		t.Errorf("expected ErrNoModels, got %v", err)
+	}
+
+	_, err = gobergamot.NewRegistry(ctx, gobergamot.RegistryConfig{
+		Models: map[gobergamot.LanguagePair]gobergamot.FilesBundle{
+			{From: "en", To: "ru"}: {Model: bytes.NewReader(nil)},
+		},
+	})
+	if !errors.Is(err, gobergamot.ErrVocabularyMissing) || !errors.Is(err, gobergamot.ErrLexicalShortlistMissing) {
+		t.Errorf("expected missing files errors, got %v", err)
+	}
+
+	_, err = gobergamot.NewRegistry(ctx, gobergamot.RegistryConfig{
+		Config: gobergamot.Config{MaxMemoryBytes: 1024, MaxInputBytes: -1},
+		Models: map[gobergamot.LanguagePair]gobergamot.FilesBundle{
+			{From: "en", To: "ru"}: testBundle(t),
+		},
+	})
+	if err == nil {
+		t.Error("expected invalid limits error, got nil")
+	}
+}
+
+func TestRegistry_Translate(t *testing.T) {
+	ctx := context.Background()
+
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	registry, err := gobergamot.NewRegistry(ctx, gobergamot.RegistryConfig{
+		Config: gobergamot.Config{
+			CompileConfig: wasm.CompileConfig{
+				Stderr: stderr,
+				Stdout: stdout,
+			},
+		},
+		Models: map[gobergamot.LanguagePair]gobergamot.FilesBundle{
+			{From: "en", To: "ru"}: testBundle(t),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+	defer func() {
+		if err := registry.Close(ctx); err != nil {
+			t.Fatalf("failed to close registry: %v", err)
+		}
+	}()
+
+	if !registry.Supports("en", "ru") {
+		t.Errorf("expected registry to support en-ru")
+	}
+	if registry.Supports("es", "ru") {
+		t.Errorf("expected registry not to support es-ru")
+	}
+
+	output, err := registry.Translate(ctx, gobergamot.TranslationRequest{Text: "Hello World", From: "en", To: "ru"})
+	if err != nil {
+		t.Fatalf("got error %v\n\nstdout: %s\n\nstderr: %s", err, stdout.String(), stderr.String())
+	}
+	if output != helloWorldTranslation {
+		t.Errorf("\nexpected: %s\ngot: %s", helloWorldTranslation, output)
+	}
+
+	_, err = registry.Translate(ctx, gobergamot.TranslationRequest{Text: "Hola Mundo", From: "es", To: "ru"})
+	if !errors.Is(err, gobergamot.ErrUnsupportedLanguagePair) {
+		t.Errorf("expected ErrUnsupportedLanguagePair, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package gobergamot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KSpaceer/gobergamot/internal/gen"
+)
+
+// ByteRange represents a half-open range [Begin, End) of bytes in a text.
+type ByteRange struct {
+	Begin int
+	End   int
+}
+
+// TranslationResult contains translated text with information about sentences
+// of the original and translated texts.
+type TranslationResult struct {
+	// Original text. If HTML option was used, it does not contain HTML tags.
+	Source string
+	// Translated text
+	Text string
+	// Sentences of the original and translated texts
+	Sentences []SentenceResult
+	// Glossary entries applied to the text. It is set only if a glossary is used.
+	Glossary []GlossaryEntry
+}
+
+// SentenceResult describes a single sentence of the original text and its translation.
+type SentenceResult struct {
+	// Range of the sentence in TranslationResult.Source
+	Source ByteRange
+	// Range of the translated sentence in TranslationResult.Text
+	Target ByteRange
+}
+
+func readTranslationResult(ctx context.Context, _ uint32, response *gen.ClassResponse) (TranslationResult, error) {
+	var (
+		result TranslationResult
+		err    error
+	)
+	result.Source, err = response.GetOriginalText(ctx)
+	if err != nil {
+		return TranslationResult{}, err
+	}
+	result.Text, err = response.GetTranslatedText(ctx)
+	if err != nil {
+		return TranslationResult{}, err
+	}
+	n, err := response.Size(ctx)
+	if err != nil {
+		return TranslationResult{}, err
+	}
+	result.Sentences = make([]SentenceResult, 0, n)
+	for i := uint32(0); i < n; i++ {
+		sentence, err := readSentenceResult(ctx, response, i)
+		if err != nil {
+			return TranslationResult{}, fmt.Errorf("failed to read sentence %d: %w", i, err)
+		}
+		result.Sentences = append(result.Sentences, sentence)
+	}
+	return result, nil
+}
+
+func readSentenceResult(ctx context.Context, response *gen.ClassResponse, idx uint32) (SentenceResult, error) {
+	var sentence SentenceResult
+
+	rawRange, err := response.GetSourceSentence(ctx, idx)
+	if err != nil {
+		return SentenceResult{}, err
+	}
+	if sentence.Source, err = byteRangeFromMap(rawRange); err != nil {
+		return SentenceResult{}, err
+	}
+
+	rawRange, err = response.GetTranslatedSentence(ctx, idx)
+	if err != nil {
+		return SentenceResult{}, err
+	}
+	if sentence.Target, err = byteRangeFromMap(rawRange); err != nil {
+		return SentenceResult{}, err
+	}
+
+	return sentence, nil
+}
+
+// byteRangeFromMap converts Bergamot ByteRange value object into ByteRange.
+func byteRangeFromMap(m map[string]any) (ByteRange, error) {
+	begin, ok := m["begin"].(uint32)
+	if !ok {
+		return ByteRange{}, fmt.Errorf("expected byte range begin to be uint32 but got %T", m["begin"])
+	}
+	end, ok := m["end"].(uint32)
+	if !ok {
+		return ByteRange{}, fmt.Errorf("expected byte range end to be uint32 but got %T", m["end"])
+	}
+	return ByteRange{Begin: int(begin), End: int(end)}, nil
+}
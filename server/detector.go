@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// script is a writing system of a language.
+type script int
+
+const (
+	scriptLatin script = iota
+	scriptCyrillic
+	scriptGreek
+	scriptArabic
+	scriptHan
+	scriptKana
+	scriptHangul
+	scriptCount
+)
+
+// languageProfile describes features of a language recognized by the built-in detector.
+type languageProfile struct {
+	script script
+	// frequent short words of the language
+	words []string
+	// letters which are rare in other languages of the same script
+	letters string
+}
+
+// languageProfiles contains profiles of languages supported by
+// https://github.com/mozilla/firefox-translations-models
+var languageProfiles = map[string]languageProfile{
+	"ar": {script: scriptArabic, words: strings.Fields("في من على أن إلى هذا هذه التي الذي عن ما لا هو مع كان"), letters: "ةىأإ"},
+	"bg": {script: scriptCyrillic, words: strings.Fields("и в не на че да е с за се от това как са ще има"), letters: "ъщ"},
+	"ca": {script: scriptLatin, words: strings.Fields("el la els les de que i és un una amb per no del als aquest però"), letters: "çàèòï"},
+	"cs": {script: scriptLatin, words: strings.Fields("a je se na v že to s do jsem jak ale není pro by také"), letters: "řěů"},
+	"da": {script: scriptLatin, words: strings.Fields("og er at det en på i jeg ikke med for har af til den de"), letters: "øæå"},
+	"de": {script: scriptLatin, words: strings.Fields("der die das und ist nicht ein eine ich du mit zu den von sie es auf für"), letters: "ßäöü"},
+	"el": {script: scriptGreek},
+	"en": {script: scriptLatin, words: strings.Fields("the and is are of to in that it with for was this you have not be on")},
+	"es": {script: scriptLatin, words: strings.Fields("el la los las de que y en es un una por con para no se del está hola"), letters: "ñ¿¡áéíóú"},
+	"et": {script: scriptLatin, words: strings.Fields("ja on ei et see oli ka kui ma mis tema aga"), letters: "õäöü"},
+	"fa": {script: scriptArabic, words: strings.Fields("و در به از که این است را با برای آن یک"), letters: "پچژگکی"},
+	"fi": {script: scriptLatin, words: strings.Fields("ja on ei se että hän oli ovat kanssa mutta tämä minä"), letters: "äö"},
+	"fr": {script: scriptLatin, words: strings.Fields("le la les de des et est un une que dans pour pas vous je il du sur bonjour"), letters: "çéèêàùœ"},
+	"hr": {script: scriptLatin, words: strings.Fields("i je u da se na za ne su od to sam što kako"), letters: "čćđšž"},
+	"hu": {script: scriptLatin, words: strings.Fields("a az és egy hogy nem is van meg de ez ki mint"), letters: "őűáéö"},
+	"id": {script: scriptLatin, words: strings.Fields("dan yang di ini itu dengan untuk tidak ada saya dari ke")},
+	"is": {script: scriptLatin, words: strings.Fields("og að er í á það sem ekki ég við með um til"), letters: "þðæ"},
+	"it": {script: scriptLatin, words: strings.Fields("il lo la gli di che e è un una per non sono con del della ciao"), letters: "àèìòù"},
+	"ja": {script: scriptKana},
+	"ko": {script: scriptHangul},
+	"lt": {script: scriptLatin, words: strings.Fields("ir yra kad tai su į bet kaip ne aš iš"), letters: "ėįųąčšž"},
+	"lv": {script: scriptLatin, words: strings.Fields("un ir ka ar uz par no tas kā bet es nav"), letters: "āēīūģķļņ"},
+	"nb": {script: scriptLatin, words: strings.Fields("og er at det en på i jeg ikke med for har av til som"), letters: "øæå"},
+	"nl": {script: scriptLatin, words: strings.Fields("de het een en is van niet dat ik je met op zijn voor te hallo")},
+	"nn": {script: scriptLatin, words: strings.Fields("og er at det ein på i eg ikkje med for har av til som"), letters: "øæå"},
+	"pl": {script: scriptLatin, words: strings.Fields("i w nie się jest na to że z do jak co ale czy tak"), letters: "ąęłńśźż"},
+	"pt": {script: scriptLatin, words: strings.Fields("o a os as de que e é um uma não para com do da em você olá"), letters: "ãõçâê"},
+	"ro": {script: scriptLatin, words: strings.Fields("și în este un o de la nu cu pe care să sunt pentru acest"), letters: "ăâîșț"},
+	"ru": {script: scriptCyrillic, words: strings.Fields("и в не на что я с он это как по но а к у"), letters: "ыэё"},
+	"sk": {script: scriptLatin, words: strings.Fields("a je sa na v že to s do som ako ale nie pre by"), letters: "ľĺŕôä"},
+	"sl": {script: scriptLatin, words: strings.Fields("in je da se na v za ne so pa ki to sem tudi"), letters: "čšž"},
+	"sq": {script: scriptLatin, words: strings.Fields("dhe në është një të e për me nga që nuk jam"), letters: "ëç"},
+	"sr": {script: scriptCyrillic, words: strings.Fields("и је у да се на за не су од то сам што како"), letters: "јљњћђџ"},
+	"sv": {script: scriptLatin, words: strings.Fields("och är att det som en på i jag inte med för har av"), letters: "åäö"},
+	"tr": {script: scriptLatin, words: strings.Fields("ve bir bu da de için ile ne değil çok ben olarak"), letters: "ğışçö"},
+	"uk": {script: scriptCyrillic, words: strings.Fields("і в не на що я з він це як та але у до"), letters: "іїєґ"},
+	"vi": {script: scriptLatin, words: strings.Fields("và là của có không một những các được cho tôi trong"), letters: "ăâđêôơư"},
+	"zh": {script: scriptHan},
+}
+
+// detector is a built-in Detector recognizing languages by their scripts, frequent words and specific letters.
+type detector struct {
+	languages []string
+}
+
+// NewDetector creates a Detector recognizing given languages. It doesn't need any models: languages are
+// recognized by their scripts, frequent words and specific letters, which is enough for sentences and
+// longer texts, while short phrases may be detected with low confidence. Languages unknown to the detector
+// are ignored. It is used by Server if Config.Detector is not set.
+func NewDetector(languages ...string) Detector {
+	d := &detector{}
+	for _, lang := range languages {
+		if _, ok := languageProfiles[lang]; ok {
+			d.languages = append(d.languages, lang)
+		}
+	}
+	sort.Strings(d.languages)
+	return d
+}
+
+func (d *detector) Detect(_ context.Context, text string) ([]Detection, error) {
+	var (
+		scripts [scriptCount]int
+		letters int
+		runes   = make(map[rune]int)
+	)
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		runes[r]++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			scripts[scriptLatin]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts[scriptCyrillic]++
+		case unicode.Is(unicode.Greek, r):
+			scripts[scriptGreek]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts[scriptArabic]++
+		case unicode.Is(unicode.Han, r):
+			scripts[scriptHan]++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts[scriptKana]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts[scriptHangul]++
+		}
+	}
+	if letters == 0 {
+		return []Detection{}, nil
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r)
+	})
+
+	scores := make(map[string]float64, len(d.languages))
+	var total float64
+	for _, lang := range d.languages {
+		profile := languageProfiles[lang]
+		share := float64(scripts[profile.script]) / float64(letters)
+		switch profile.script {
+		case scriptKana:
+			// Japanese texts mix kana with kanji
+			share = float64(scripts[scriptKana]+scripts[scriptHan]) / float64(letters)
+			if scripts[scriptKana] == 0 {
+				share /= 4
+			}
+		case scriptHan:
+			if scripts[scriptKana] > 0 {
+				share /= 4
+			}
+		}
+		if share == 0 {
+			continue
+		}
+
+		var wordHits, letterHits int
+		for _, word := range words {
+			for _, frequent := range profile.words {
+				if word == frequent {
+					wordHits++
+					break
+				}
+			}
+		}
+		for _, r := range profile.letters {
+			letterHits += runes[r]
+		}
+		score := share * (0.05 + float64(wordHits)/float64(max(len(words), 1)) + 2*float64(letterHits)/float64(letters))
+		if profile.words == nil && profile.letters == "" {
+			// the language is recognized by its script only
+			score = share
+		}
+		scores[lang] = score
+		total += score
+	}
+
+	detections := make([]Detection, 0, len(scores))
+	for lang, score := range scores {
+		confidence := math.Round(score/total*1000) / 10
+		if confidence == 0 {
+			continue
+		}
+		detections = append(detections, Detection{Language: lang, Confidence: confidence})
+	}
+	sort.Slice(detections, func(i, j int) bool {
+		if detections[i].Confidence != detections[j].Confidence {
+			return detections[i].Confidence > detections[j].Confidence
+		}
+		return detections[i].Language < detections[j].Language
+	})
+	return detections, nil
+}
@@ -0,0 +1,52 @@
+package server
+
+// languageNames contains English names of languages supported by
+// https://github.com/mozilla/firefox-translations-models
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"bg": "Bulgarian",
+	"ca": "Catalan",
+	"cs": "Czech",
+	"da": "Danish",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"et": "Estonian",
+	"fa": "Persian",
+	"fi": "Finnish",
+	"fr": "French",
+	"hr": "Croatian",
+	"hu": "Hungarian",
+	"id": "Indonesian",
+	"is": "Icelandic",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"lt": "Lithuanian",
+	"lv": "Latvian",
+	"nb": "Norwegian Bokmål",
+	"nl": "Dutch",
+	"nn": "Norwegian Nynorsk",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ro": "Romanian",
+	"ru": "Russian",
+	"sk": "Slovak",
+	"sl": "Slovenian",
+	"sq": "Albanian",
+	"sr": "Serbian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"vi": "Vietnamese",
+	"zh": "Chinese",
+}
+
+// languageName returns English name of the language or its code if the name is unknown.
+func languageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
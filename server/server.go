@@ -0,0 +1,443 @@
+// Package server provides HTTP translation server with API compatible with LibreTranslate
+// (https://libretranslate.com/docs), so existing LibreTranslate clients can be used with gobergamot.
+//
+// Requests are handled concurrently, so translators must be safe for concurrent use. gobergamot.Pool is,
+// while gobergamot.Translator must be wrapped with Serialize.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KSpaceer/gobergamot"
+)
+
+// DefaultMaxRequestBytes is a default limit of request body size.
+const DefaultMaxRequestBytes = 1 << 20
+
+// autoSource is a source language value requesting language detection.
+const autoSource = "auto"
+
+var ErrNoTranslators = errors.New("at least one translator is required")
+
+// Translator translates texts for a single language pair. It must be safe for concurrent use,
+// e.g. gobergamot.Pool or gobergamot.Translator wrapped with Serialize.
+type Translator interface {
+	TranslateMultiple(ctx context.Context, requests ...gobergamot.TranslationRequest) ([]string, error)
+}
+
+// serializedTranslator makes translator calls one at a time.
+type serializedTranslator struct {
+	mu sync.Mutex
+	tr Translator
+}
+
+// Serialize makes the translator safe for concurrent use by making concurrent calls wait for each other.
+// It allows serving a single gobergamot.Translator, which is not safe for concurrent use.
+func Serialize(tr Translator) Translator {
+	return &serializedTranslator{tr: tr}
+}
+
+func (t *serializedTranslator) TranslateMultiple(
+	ctx context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tr.TranslateMultiple(ctx, requests...)
+}
+
+// Detection is a result of language detection.
+type Detection struct {
+	// Code of the detected language
+	Language string `json:"language"`
+	// Confidence of the detection in percents
+	Confidence float64 `json:"confidence"`
+}
+
+// Detector detects language of texts. Detections must be sorted by confidence in descending order.
+type Detector interface {
+	Detect(ctx context.Context, text string) ([]Detection, error)
+}
+
+type Config struct {
+	// Translators for the supported language pairs. Required
+	Translators map[gobergamot.LanguagePair]Translator
+
+	// Detector is used by /detect endpoint and for requests with "auto" source language.
+	// Defaults to NewDetector with source languages of the translators.
+	Detector Detector
+
+	// PivotLanguage is a language to translate via if there is no direct translator for requested language pair.
+	// Defaults to gobergamot.DefaultPivotLanguage.
+	PivotLanguage string
+
+	// MaxRequestBytes limits size of request bodies. Defaults to DefaultMaxRequestBytes.
+	MaxRequestBytes int64
+}
+
+func (cfg Config) Validate() error {
+	if len(cfg.Translators) == 0 {
+		return ErrNoTranslators
+	}
+	return nil
+}
+
+// Server is a http.Handler serving LibreTranslate API endpoints:
+//
+//	POST /translate - translates texts
+//	GET /languages - lists supported languages
+//	POST /detect - detects language of text
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+}
+
+// New creates a Server using translators from the config.
+func New(cfg Config) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.PivotLanguage == "" {
+		cfg.PivotLanguage = gobergamot.DefaultPivotLanguage
+	}
+	if cfg.MaxRequestBytes <= 0 {
+		cfg.MaxRequestBytes = DefaultMaxRequestBytes
+	}
+	if cfg.Detector == nil {
+		sources := make([]string, 0, len(cfg.Translators))
+		for pair := range cfg.Translators {
+			sources = append(sources, pair.From)
+		}
+		cfg.Detector = NewDetector(sources...)
+	}
+
+	s := &Server{
+		cfg: cfg,
+		mux: http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /translate", s.handleTranslate)
+	s.mux.HandleFunc("GET /languages", s.handleLanguages)
+	s.mux.HandleFunc("POST /detect", s.handleDetect)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type translateRequest struct {
+	Q      json.RawMessage `json:"q"`
+	Source string          `json:"source"`
+	Target string          `json:"target"`
+	Format string          `json:"format"`
+}
+
+type translateResponse struct {
+	TranslatedText   any `json:"translatedText"`
+	DetectedLanguage any `json:"detectedLanguage,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type language struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Targets []string `json:"targets"`
+}
+
+func (s *Server) handleTranslate(w http.ResponseWriter, r *http.Request) {
+	var req translateRequest
+	if err := s.decodeRequest(w, r, &req); err != nil {
+		writeError(w, decodeErrorStatus(err), err.Error())
+		return
+	}
+
+	texts, batch, err := parseQuery(req.Q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Source == "" {
+		writeError(w, http.StatusBadRequest, "Invalid request: missing source parameter")
+		return
+	}
+	if req.Target == "" {
+		writeError(w, http.StatusBadRequest, "Invalid request: missing target parameter")
+		return
+	}
+	var options gobergamot.TranslationOptions
+	switch req.Format {
+	case "", "text":
+	case "html":
+		options.HTML = true
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: unsupported format %q", req.Format))
+		return
+	}
+
+	sources := make([]string, len(texts))
+	var detections []Detection
+	if req.Source == autoSource {
+		detections = make([]Detection, len(texts))
+		for i := range texts {
+			detected, err := s.cfg.Detector.Detect(r.Context(), texts[i])
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to detect language: %v", err))
+				return
+			}
+			if len(detected) == 0 {
+				writeError(w, http.StatusBadRequest, "Failed to detect language")
+				return
+			}
+			detections[i] = detected[0]
+			sources[i] = detected[0].Language
+		}
+	} else {
+		for i := range sources {
+			sources[i] = req.Source
+		}
+	}
+
+	translated, err := s.translate(r.Context(), texts, sources, req.Target, options)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gobergamot.ErrUnsupportedLanguagePair):
+			status = http.StatusBadRequest
+		case errors.Is(err, gobergamot.ErrInputTooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, gobergamot.ErrQueueFull):
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	var resp translateResponse
+	if batch {
+		resp.TranslatedText = translated
+		if detections != nil {
+			resp.DetectedLanguage = detections
+		}
+	} else {
+		resp.TranslatedText = translated[0]
+		if detections != nil {
+			resp.DetectedLanguage = detections[0]
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleLanguages(w http.ResponseWriter, _ *http.Request) {
+	codes := make(map[string]struct{})
+	for pair := range s.cfg.Translators {
+		codes[pair.From] = struct{}{}
+		codes[pair.To] = struct{}{}
+	}
+
+	languages := make([]language, 0, len(codes))
+	for code := range codes {
+		lang := language{Code: code, Name: languageName(code), Targets: []string{}}
+		for target := range codes {
+			if target == code {
+				continue
+			}
+			if _, err := s.route(gobergamot.LanguagePair{From: code, To: target}); err == nil {
+				lang.Targets = append(lang.Targets, target)
+			}
+		}
+		sort.Strings(lang.Targets)
+		languages = append(languages, lang)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		return languages[i].Code < languages[j].Code
+	})
+	writeJSON(w, http.StatusOK, languages)
+}
+
+func (s *Server) handleDetect(w http.ResponseWriter, r *http.Request) {
+	var req translateRequest
+	if err := s.decodeRequest(w, r, &req); err != nil {
+		writeError(w, decodeErrorStatus(err), err.Error())
+		return
+	}
+	texts, _, err := parseQuery(req.Q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	detections, err := s.cfg.Detector.Detect(r.Context(), strings.Join(texts, "\n"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to detect language: %v", err))
+		return
+	}
+	if detections == nil {
+		detections = []Detection{}
+	}
+	writeJSON(w, http.StatusOK, detections)
+}
+
+// translate translates texts with given source languages into target language.
+// Texts with the same source language are translated in a single call.
+func (s *Server) translate(
+	ctx context.Context,
+	texts, sources []string,
+	target string,
+	options gobergamot.TranslationOptions,
+) ([]string, error) {
+	var (
+		order  []string
+		groups = make(map[string][]int)
+	)
+	for i, source := range sources {
+		if _, ok := groups[source]; !ok {
+			order = append(order, source)
+		}
+		groups[source] = append(groups[source], i)
+	}
+
+	output := make([]string, len(texts))
+	for _, source := range order {
+		indices := groups[source]
+		if source == target {
+			for _, idx := range indices {
+				output[idx] = texts[idx]
+			}
+			continue
+		}
+		translators, err := s.route(gobergamot.LanguagePair{From: source, To: target})
+		if err != nil {
+			return nil, err
+		}
+		groupTexts := make([]string, len(indices))
+		for i, idx := range indices {
+			groupTexts[i] = texts[idx]
+		}
+		for _, tr := range translators {
+			groupTexts, err = translateTexts(ctx, tr, groupTexts, options)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i, idx := range indices {
+			output[idx] = groupTexts[i]
+		}
+	}
+	return output, nil
+}
+
+// route returns translators to apply sequentially to translate texts for given language pair.
+func (s *Server) route(pair gobergamot.LanguagePair) ([]Translator, error) {
+	if tr, ok := s.cfg.Translators[pair]; ok {
+		return []Translator{tr}, nil
+	}
+	pivot := s.cfg.PivotLanguage
+	if pair.From != pivot && pair.To != pivot {
+		first, ok := s.cfg.Translators[gobergamot.LanguagePair{From: pair.From, To: pivot}]
+		second, pivotOk := s.cfg.Translators[gobergamot.LanguagePair{From: pivot, To: pair.To}]
+		if ok && pivotOk {
+			return []Translator{first, second}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", gobergamot.ErrUnsupportedLanguagePair, pair)
+}
+
+func translateTexts(
+	ctx context.Context,
+	tr Translator,
+	texts []string,
+	options gobergamot.TranslationOptions,
+) ([]string, error) {
+	requests := make([]gobergamot.TranslationRequest, len(texts))
+	for i := range texts {
+		requests[i] = gobergamot.TranslationRequest{Text: texts[i], Options: options}
+	}
+	translated, err := tr.TranslateMultiple(ctx, requests...)
+	if err != nil {
+		return nil, err
+	}
+	if len(translated) != len(texts) {
+		return nil, fmt.Errorf("expected %d translated texts, got %d", len(texts), len(translated))
+	}
+	return translated, nil
+}
+
+// decodeRequest decodes JSON or form request body like LibreTranslate does.
+func (s *Server) decodeRequest(w http.ResponseWriter, r *http.Request, req *translateRequest) error {
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxRequestBytes)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return fmt.Errorf("Invalid request: %w", err)
+		}
+		return nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Invalid request: %w", err)
+	}
+	var (
+		q   any
+		err error
+	)
+	switch values := r.PostForm["q"]; len(values) {
+	case 0:
+	case 1:
+		q = values[0]
+	default:
+		q = values
+	}
+	if q != nil {
+		if req.Q, err = json.Marshal(q); err != nil {
+			return err
+		}
+	}
+	req.Source = r.PostForm.Get("source")
+	req.Target = r.PostForm.Get("target")
+	req.Format = r.PostForm.Get("format")
+	return nil
+}
+
+// decodeErrorStatus returns HTTP status for decodeRequest error.
+func decodeErrorStatus(err error) int {
+	if errors.As(err, new(*http.MaxBytesError)) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// parseQuery parses q parameter which can be either a single text or an array of texts.
+func parseQuery(q json.RawMessage) (texts []string, batch bool, err error) {
+	if len(q) == 0 {
+		return nil, false, errors.New("Invalid request: missing q parameter")
+	}
+	var text string
+	if err := json.Unmarshal(q, &text); err == nil {
+		return []string{text}, false, nil
+	}
+	if err := json.Unmarshal(q, &texts); err != nil {
+		return nil, false, errors.New("Invalid request: q must be a string or an array of strings")
+	}
+	if len(texts) == 0 {
+		return nil, false, errors.New("Invalid request: missing q parameter")
+	}
+	return texts, true, nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,320 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/server"
+)
+
+// prefixTranslator "translates" texts by prefixing them with target language code.
+type prefixTranslator struct {
+	to string
+}
+
+func (t prefixTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	outputs := make([]string, len(requests))
+	for i := range requests {
+		if len(requests[i].Text) > maxTestInputBytes {
+			return nil, gobergamot.ErrInputTooLarge
+		}
+		outputs[i] = t.to + ":" + requests[i].Text
+	}
+	return outputs, nil
+}
+
+// maxTestInputBytes is a text size limit of prefixTranslator
+const maxTestInputBytes = 64
+
+type staticDetector struct{}
+
+func (staticDetector) Detect(_ context.Context, text string) ([]server.Detection, error) {
+	if strings.HasPrefix(text, "hola") {
+		return []server.Detection{{Language: "es", Confidence: 90}}, nil
+	}
+	return []server.Detection{{Language: "en", Confidence: 80}}, nil
+}
+
+func newTestServer(t *testing.T, detector server.Detector) *server.Server {
+	t.Helper()
+	srv, err := server.New(server.Config{
+		Translators: map[gobergamot.LanguagePair]server.Translator{
+			{From: "en", To: "ru"}: prefixTranslator{to: "ru"},
+			{From: "es", To: "en"}: prefixTranslator{to: "en"},
+		},
+		Detector: detector,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	return srv
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := server.New(server.Config{})
+	if err != server.ErrNoTranslators {
+		t.Errorf("expected ErrNoTranslators, got %v", err)
+	}
+}
+
+func TestServer_Translate(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		detector     server.Detector
+		expectedCode int
+		expected     map[string]any
+	}{
+		{
+			name:         "single text",
+			body:         `{"q": "hello", "source": "en", "target": "ru"}`,
+			expectedCode: http.StatusOK,
+			expected:     map[string]any{"translatedText": "ru:hello"},
+		},
+		{
+			name:         "multiple texts",
+			body:         `{"q": ["hello", "world"], "source": "en", "target": "ru", "format": "html"}`,
+			expectedCode: http.StatusOK,
+			expected:     map[string]any{"translatedText": []any{"ru:hello", "ru:world"}},
+		},
+		{
+			name:         "pivoting",
+			body:         `{"q": "hola", "source": "es", "target": "ru"}`,
+			expectedCode: http.StatusOK,
+			expected:     map[string]any{"translatedText": "ru:en:hola"},
+		},
+		{
+			name:         "auto source",
+			body:         `{"q": ["hola", "hello"], "source": "auto", "target": "ru"}`,
+			detector:     staticDetector{},
+			expectedCode: http.StatusOK,
+			expected: map[string]any{
+				"translatedText": []any{"ru:en:hola", "ru:hello"},
+				"detectedLanguage": []any{
+					map[string]any{"language": "es", "confidence": 90.0},
+					map[string]any{"language": "en", "confidence": 80.0},
+				},
+			},
+		},
+		{
+			name:         "auto source with built-in detector",
+			body:         `{"q": "¿Dónde está la estación?", "source": "auto", "target": "en"}`,
+			expectedCode: http.StatusOK,
+			expected: map[string]any{
+				"translatedText":   "en:¿Dónde está la estación?",
+				"detectedLanguage": map[string]any{"language": "es", "confidence": 94.5},
+			},
+		},
+		{
+			name:         "input too large",
+			body:         `{"q": "` + strings.Repeat("hello ", 20) + `", "source": "en", "target": "ru"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "request too large",
+			body:         `{"q": "` + strings.Repeat("a", server.DefaultMaxRequestBytes) + `", "source": "en", "target": "ru"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "unsupported language pair",
+			body:         `{"q": "hello", "source": "ru", "target": "es"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "missing q",
+			body:         `{"source": "en", "target": "ru"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid format",
+			body:         `{"q": "hello", "source": "en", "target": "ru", "format": "markdown"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestServer(t, tc.detector)
+
+			req := httptest.NewRequest(http.MethodPost, "/translate", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedCode, rec.Code, rec.Body)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if tc.expectedCode != http.StatusOK {
+				if body["error"] == nil {
+					t.Errorf("expected error message in response, got %v", body)
+				}
+				return
+			}
+			if !reflect.DeepEqual(body, tc.expected) {
+				t.Errorf("expected response %v, got %v", tc.expected, body)
+			}
+		})
+	}
+}
+
+func TestServer_TranslateForm(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	form := url.Values{"q": {"hello"}, "source": {"en"}, "target": {"ru"}}
+	req := httptest.NewRequest(http.MethodPost, "/translate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.TranslatedText != "ru:hello" {
+		t.Errorf("expected translated text %q, got %q", "ru:hello", body.TranslatedText)
+	}
+}
+
+func TestServer_Languages(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/languages", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	type language struct {
+		Code    string   `json:"code"`
+		Name    string   `json:"name"`
+		Targets []string `json:"targets"`
+	}
+	var languages []language
+	if err := json.Unmarshal(rec.Body.Bytes(), &languages); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	expected := []language{
+		{Code: "en", Name: "English", Targets: []string{"ru"}},
+		{Code: "es", Name: "Spanish", Targets: []string{"en", "ru"}},
+		{Code: "ru", Name: "Russian", Targets: []string{}},
+	}
+	if len(languages) != len(expected) {
+		t.Fatalf("expected %d languages, got %v", len(expected), languages)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(languages[i], expected[i]) {
+			t.Errorf("expected language %v, got %v", expected[i], languages[i])
+		}
+	}
+}
+
+func TestServer_Detect(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/detect", strings.NewReader(`{"q": "hola"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newTestServer(t, staticDetector{}).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var detections []server.Detection
+	if err := json.Unmarshal(rec.Body.Bytes(), &detections); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	expected := []server.Detection{{Language: "es", Confidence: 90}}
+	if !reflect.DeepEqual(detections, expected) {
+		t.Errorf("expected detections %v, got %v", expected, detections)
+	}
+}
+
+func TestNewDetector(t *testing.T) {
+	detector := server.NewDetector("en", "es", "de", "fr", "ru", "uk", "ja", "zh", "unknown")
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "Hello, how are you doing today? This is a test.", expected: "en"},
+		{text: "¡Hola, Mundo! ¿Cómo estás? Esta es una prueba.", expected: "es"},
+		{text: "Hallo Welt, ich bin sehr froh, dich zu sehen.", expected: "de"},
+		{text: "Bonjour le monde, je suis très content de vous voir.", expected: "fr"},
+		{text: "Привет, мир! Это простой тест детектора языка.", expected: "ru"},
+		{text: "Привіт, світ! Це простий тест детектора мови.", expected: "uk"},
+		{text: "こんにちは世界！これは言語検出のテストです。", expected: "ja"},
+		{text: "你好世界！这是一个语言检测测试。", expected: "zh"},
+	}
+	for _, tc := range tests {
+		detections, err := detector.Detect(context.Background(), tc.text)
+		if err != nil {
+			t.Fatalf("failed to detect language of %q: %v", tc.text, err)
+		}
+		if len(detections) == 0 || detections[0].Language != tc.expected {
+			t.Errorf("expected %q to be detected as %s, got %v", tc.text, tc.expected, detections)
+		}
+		for i := 1; i < len(detections); i++ {
+			if detections[i].Confidence > detections[i-1].Confidence {
+				t.Errorf("detections are not sorted by confidence: %v", detections)
+			}
+		}
+	}
+
+	detections, err := detector.Detect(context.Background(), "123 !?")
+	if err != nil || len(detections) != 0 {
+		t.Errorf("expected no detections for text without letters, got %v, %v", detections, err)
+	}
+}
+
+// exclusiveTranslator fails if it is called concurrently.
+type exclusiveTranslator struct {
+	active atomic.Int64
+}
+
+func (t *exclusiveTranslator) TranslateMultiple(
+	_ context.Context,
+	requests ...gobergamot.TranslationRequest,
+) ([]string, error) {
+	defer t.active.Add(-1)
+	if t.active.Add(1) != 1 {
+		return nil, errors.New("concurrent call")
+	}
+	time.Sleep(time.Millisecond)
+	return make([]string, len(requests)), nil
+}
+
+func TestSerialize(t *testing.T) {
+	tr := server.Serialize(&exclusiveTranslator{})
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := tr.TranslateMultiple(context.Background(), gobergamot.TranslationRequest{Text: "hello"})
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("failed to translate: %v", err)
+		}
+	}
+}
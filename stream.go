@@ -0,0 +1,145 @@
+package gobergamot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// StreamedSentence is a translated sentence sent by TranslateStream.
+type StreamedSentence struct {
+	// Range of the sentence in the original text
+	Source ByteRange
+	// Translated sentence
+	Text string
+	// Err is set if translation failed. Such value is the last one sent.
+	Err error
+}
+
+// TranslateStream translates the text sentence by sentence and sends translated sentences to the returned channel
+// as soon as they are ready, so callers can show progressive translation even for short texts. Replacing source
+// ranges of all sentences in the original text with their translations gives the whole translation.
+// The channel is closed after the last sentence or an error, when the translator is not used by the stream anymore.
+//
+// If HTML option or Placeholders option is used, the text is split only into paragraphs like in TranslateDocument,
+// and paragraphs are sent instead of sentences.
+// The caller must either read the channel until it is closed or cancel the context.
+func (t *Translator) TranslateStream(ctx context.Context, request TranslationRequest) <-chan StreamedSentence {
+	return translateStream(ctx, request, t.cfg.MaxInputBytes, 1, t.TranslateMultiple)
+}
+
+// TranslateStream is similar to Translator.TranslateStream except sentences are translated by the pool workers
+// in parallel. Sentences are still sent in order of the text.
+func (p *Pool) TranslateStream(ctx context.Context, request TranslationRequest) <-chan StreamedSentence {
+	return translateStream(ctx, request, p.cfg.MaxInputBytes, int(max(p.Size(), 1)), p.TranslateMultiple)
+}
+
+// streamChunk is a chunk of the streamed text.
+type streamChunk struct {
+	offset int
+	text   string
+	result chan streamChunkResult
+}
+
+type streamChunkResult struct {
+	outputs []string
+	err     error
+}
+
+// translateStream splits the text into chunks, translates up to parallelism chunks at once and sends
+// them in order. Plain texts are split into sentences, so every chunk is a sentence.
+// The channel is closed after all started translations are finished.
+func translateStream(
+	ctx context.Context,
+	request TranslationRequest,
+	maxInputBytes int,
+	parallelism int,
+	translate func(ctx context.Context, requests ...TranslationRequest) ([]string, error),
+) <-chan StreamedSentence {
+	chunkBytes := DocumentChunkBytes
+	if maxInputBytes > 0 && maxInputBytes < chunkBytes {
+		chunkBytes = maxInputBytes
+	}
+	var (
+		chunks []streamChunk
+		offset int
+	)
+	for _, part := range splitStream(request.Text, chunkBytes, request.Options) {
+		if part.translate {
+			chunks = append(chunks, streamChunk{
+				offset: offset,
+				text:   part.text,
+				result: make(chan streamChunkResult, 1),
+			})
+		}
+		offset += len(part.text)
+	}
+
+	out := make(chan StreamedSentence)
+	go func() {
+		// stopping translation of remaining chunks if the caller stops reading or translation fails
+		translateCtx, cancel := context.WithCancel(ctx)
+		var inFlight sync.WaitGroup
+		defer func() {
+			cancel()
+			// translator must not be used after the stream is closed
+			inFlight.Wait()
+			close(out)
+		}()
+
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			sem := make(chan struct{}, parallelism)
+			for _, chunk := range chunks {
+				select {
+				case sem <- struct{}{}:
+				case <-translateCtx.Done():
+					return
+				}
+				chunkRequest := request
+				chunkRequest.Text = chunk.text
+				inFlight.Add(1)
+				go func(chunk streamChunk) {
+					defer inFlight.Done()
+					outputs, err := translate(translateCtx, chunkRequest)
+					chunk.result <- streamChunkResult{outputs: outputs, err: err}
+					<-sem
+				}(chunk)
+			}
+		}()
+
+		send := func(sentence StreamedSentence) bool {
+			select {
+			case out <- sentence:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, chunk := range chunks {
+			var res streamChunkResult
+			select {
+			case res = <-chunk.result:
+			case <-ctx.Done():
+				send(StreamedSentence{Err: ctx.Err()})
+				return
+			}
+			if res.err == nil && len(res.outputs) != 1 {
+				res.err = fmt.Errorf("expected 1 translated text but got %d", len(res.outputs))
+			}
+			if res.err != nil {
+				send(StreamedSentence{Err: res.err})
+				return
+			}
+			sentence := StreamedSentence{
+				Source: ByteRange{Begin: chunk.offset, End: chunk.offset + len(chunk.text)},
+				Text:   res.outputs[0],
+			}
+			if !send(sentence) {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,67 @@
+package gobergamot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTranslateStream_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var active atomic.Int64
+	started := make(chan struct{}, 1)
+	translate := func(ctx context.Context, requests ...TranslationRequest) ([]string, error) {
+		active.Add(1)
+		defer active.Add(-1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		// translation ignoring the context for a while, like a WASM call does
+		time.Sleep(50 * time.Millisecond)
+		return []string{strings.ToUpper(requests[0].Text)}, nil
+	}
+
+	stream := translateStream(ctx, TranslationRequest{Text: "One. Two. Three. Four."}, 0, 2, translate)
+	<-started
+	cancel()
+
+	var lastErr error
+	for sentence := range stream {
+		lastErr = sentence.Err
+	}
+	if n := active.Load(); n != 0 {
+		t.Errorf("stream is closed with %d translations in flight", n)
+	}
+	if lastErr != nil && !errors.Is(lastErr, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", lastErr)
+	}
+}
+
+func TestTranslateStream_Sentences(t *testing.T) {
+	translate := func(ctx context.Context, requests ...TranslationRequest) ([]string, error) {
+		return []string{strings.ToUpper(requests[0].Text)}, nil
+	}
+
+	text := "Hello, World! How are you?\n\nFine."
+	var got []string
+	for sentence := range translateStream(context.Background(), TranslationRequest{Text: text}, 0, 1, translate) {
+		if sentence.Err != nil {
+			t.Fatalf("failed to translate: %v", sentence.Err)
+		}
+		got = append(got, text[sentence.Source.Begin:sentence.Source.End]+" -> "+sentence.Text)
+	}
+	want := []string{
+		"Hello, World! -> HELLO, WORLD!",
+		"How are you? -> HOW ARE YOU?",
+		"Fine. -> FINE.",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected sentences %q, got %q", want, got)
+	}
+}
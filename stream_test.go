@@ -0,0 +1,98 @@
+package gobergamot_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KSpaceer/gobergamot"
+	"github.com/KSpaceer/gobergamot/internal/wasm"
+)
+
+func TestPool_TranslateStream(t *testing.T) {
+	ctx := context.Background()
+
+	pool, err := gobergamot.NewPool(ctx, gobergamot.PoolConfig{
+		Config:   gobergamot.Config{FilesBundle: testBundle(t)},
+		PoolSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close(ctx)
+
+	text := "Hello, World!\n\nComputers have opened up new possibilities. Invalid format: invalid regex\n"
+	var (
+		sentences []gobergamot.StreamedSentence
+		prevEnd   int
+	)
+	for sentence := range pool.TranslateStream(ctx, gobergamot.TranslationRequest{Text: text}) {
+		if sentence.Err != nil {
+			t.Fatalf("failed to translate: %v", sentence.Err)
+		}
+		if sentence.Source.Begin < prevEnd || sentence.Source.End > len(text) {
+			t.Fatalf("unexpected source range %v after %d", sentence.Source, prevEnd)
+		}
+		prevEnd = sentence.Source.End
+		sentences = append(sentences, sentence)
+	}
+
+	want := []struct {
+		source, text string
+	}{
+		{source: "Hello, World!", text: "Здравствуйте, Мир!"},
+		{source: "Computers have opened up new possibilities.", text: "Компьютеры открыли новые возможности."},
+		{source: "Invalid format: invalid regex", text: "Неверный формат: недействительный regex"},
+	}
+	if len(sentences) != len(want) {
+		t.Fatalf("expected %d sentences, got %d: %+v", len(want), len(sentences), sentences)
+	}
+	for i, sentence := range sentences {
+		source := text[sentence.Source.Begin:sentence.Source.End]
+		if strings.TrimSpace(source) != want[i].source || strings.TrimSpace(sentence.Text) != want[i].text {
+			t.Errorf("sentence %d: expected %q -> %q, got %q -> %q", i, want[i].source, want[i].text, source, sentence.Text)
+		}
+	}
+}
+
+func TestTranslator_TranslateStream_Cancel(t *testing.T) {
+	ctx := context.Background()
+
+	stderr := new(strings.Builder)
+	translator, err := gobergamot.New(ctx, gobergamot.Config{
+		CompileConfig: wasm.CompileConfig{Stderr: stderr},
+		FilesBundle:   testBundle(t),
+	})
+	if err != nil {
+		t.Fatalf("failed to create translator: %v\n\nstderr: %s", err, stderr.String())
+	}
+	defer func() {
+		if err := translator.Close(ctx); err != nil {
+			t.Fatalf("failed to close translator: %v", err)
+		}
+	}()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	text := strings.Repeat("Computers have opened up new possibilities. ", 20)
+	stream := translator.TranslateStream(streamCtx, gobergamot.TranslationRequest{Text: text})
+	if sentence := <-stream; sentence.Err != nil {
+		t.Fatalf("failed to translate: %v", sentence.Err)
+	}
+	cancel()
+	for sentence := range stream {
+		if sentence.Err != nil && !errors.Is(sentence.Err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", sentence.Err)
+		}
+	}
+
+	// the stream is closed after its translations, so the translator can be used right away
+	output, err := translator.Translate(ctx, gobergamot.TranslationRequest{Text: "Hello World"})
+	if err != nil {
+		t.Fatalf("failed to translate after cancelled stream: %v", err)
+	}
+	if output != helloWorldTranslation {
+		t.Errorf("unexpected output %s", output)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	embind "github.com/jerbob92/wazero-emscripten-embind"
@@ -24,8 +26,15 @@ type FilesBundle struct {
 	Model io.Reader
 	// Byte array of shortlist. Required
 	LexicalShortlist io.Reader
-	// Byte array of vocabulary to translate between source and target languages. Required
+	// Byte array of vocabulary to translate between source and target languages.
+	// Required, unless SourceVocabulary and TargetVocabulary are provided.
 	Vocabulary io.Reader
+	// Byte arrays of separate vocabularies for source and target languages.
+	// Required if Vocabulary is not provided.
+	SourceVocabulary io.Reader
+	TargetVocabulary io.Reader
+	// Byte array of quality estimation model. Optional.
+	QualityModel io.Reader
 }
 
 type Config struct {
@@ -42,6 +51,11 @@ type Config struct {
 	// Data to load into translator
 	FilesBundle
 
+	// Pivot is an optional data of the model translating from an intermediate (pivot) language
+	// into the target language. If it is set, FilesBundle must contain the model translating
+	// from the source language into the pivot language, and texts are translated via pivoting.
+	Pivot *FilesBundle
+
 	// From Bergamot sources:
 	//
 	// Equivalent to options based constructor, where `options` is parsed from string configuration. Configuration can be
@@ -54,28 +68,81 @@ type Config struct {
 	// WASMUseContext defines if WASM functions execution must be canceled upon context.Context cancellation.
 	// Equivalent to wazero.RuntimeConfig WithCloseOnContextDone method parameter.
 	WASMUseContext bool
+
+	// MaxMemoryBytes limits size of WASM module memory, which holds the models and translation workspace.
+	// It is rounded down to WASM memory pages (64 KiB). Loading models which don't fit into the limit
+	// and running out of memory during translation fail with ErrOutOfMemory. A value of 0 means
+	// the WASM maximum of 4 GiB.
+	MaxMemoryBytes uint64
+
+	// MaxInputBytes limits size of every translated text. Translation of larger texts fails with ErrInputTooLarge,
+	// they can be translated with TranslateDocument instead. A value of 0 means no limit.
+	MaxInputBytes int
+
+	// Glossary is used to translate requests which don't have TranslationOptions.Glossary. Optional.
+	Glossary *Glossary
+
+	// Observer receives events of translators, e.g. to collect metrics. Optional.
+	Observer Observer
 }
 
 var (
 	ErrModelMissing            = errors.New("model is required")
 	ErrVocabularyMissing       = errors.New("vocabulary is required")
+	ErrSourceVocabularyMissing = errors.New("source vocabulary is required along with target vocabulary")
+	ErrTargetVocabularyMissing = errors.New("target vocabulary is required along with source vocabulary")
+	ErrAmbiguousVocabulary     = errors.New("vocabulary can not be used along with source and target vocabularies")
 	ErrLexicalShortlistMissing = errors.New("lexical shortlist is required")
+
+	// ErrOutOfMemory is returned if WASM module memory can't grow to fit the models or translation data.
+	// Translator must not be used after translation failed with it.
+	ErrOutOfMemory = wasm.ErrOutOfMemory
 )
 
-func (cfg Config) Validate() error {
+func (b FilesBundle) Validate() error {
 	var err error
-	if cfg.Model == nil {
+	if b.Model == nil {
 		err = errors.Join(err, ErrModelMissing)
 	}
-	if cfg.Vocabulary == nil {
+	switch {
+	case b.Vocabulary != nil && (b.SourceVocabulary != nil || b.TargetVocabulary != nil):
+		err = errors.Join(err, ErrAmbiguousVocabulary)
+	case b.Vocabulary != nil:
+	case b.SourceVocabulary == nil && b.TargetVocabulary == nil:
 		err = errors.Join(err, ErrVocabularyMissing)
+	case b.SourceVocabulary == nil:
+		err = errors.Join(err, ErrSourceVocabularyMissing)
+	case b.TargetVocabulary == nil:
+		err = errors.Join(err, ErrTargetVocabularyMissing)
 	}
-	if cfg.LexicalShortlist == nil {
+	if b.LexicalShortlist == nil {
 		err = errors.Join(err, ErrLexicalShortlistMissing)
 	}
 	return err
 }
 
+func (cfg Config) Validate() error {
+	err := cfg.FilesBundle.Validate()
+	if cfg.Pivot != nil {
+		if pivotErr := cfg.Pivot.Validate(); pivotErr != nil {
+			err = errors.Join(err, fmt.Errorf("pivot: %w", pivotErr))
+		}
+	}
+	return errors.Join(err, cfg.validateLimits())
+}
+
+// validateLimits validates options of Config which are not related to models.
+func (cfg Config) validateLimits() error {
+	var err error
+	if cfg.MaxInputBytes < 0 {
+		err = errors.Join(err, errors.New("MaxInputBytes must not be negative"))
+	}
+	if cfg.MaxMemoryBytes != 0 && (cfg.MaxMemoryBytes < wasmMemoryPageSize || cfg.MaxMemoryBytes > wasmMaxMemoryBytes) {
+		err = errors.Join(err, fmt.Errorf("MaxMemoryBytes must be between %d and %d", wasmMemoryPageSize, wasmMaxMemoryBytes))
+	}
+	return err
+}
+
 // DefaultBergamotOptions provides default options for WASM Bergamot translator worker
 // like in https://github.com/browsermt/bergamot-translator/blob/v0.4.5/wasm/node-test.js#L66
 func DefaultBergamotOptions() map[string]any {
@@ -94,14 +161,21 @@ func DefaultBergamotOptions() map[string]any {
 	}
 }
 
+// translatorIDs is a source of Translator IDs
+var translatorIDs atomic.Uint64
+
 // Translator represents a Bergamot translator worker in Go.
 type Translator struct {
+	// id identifies the translator in observer events
+	id uint64
+
 	embindEngine embind.Engine
 	wasmRuntime  wazero.Runtime
 	cfg          Config
 
-	model *gen.ClassTranslationModel
-	svc   *gen.ClassBlockingService
+	model      *gen.ClassTranslationModel
+	pivotModel *gen.ClassTranslationModel
+	svc        *gen.ClassBlockingService
 
 	module api.Module
 }
@@ -113,11 +187,55 @@ func New(ctx context.Context, cfg Config) (*Translator, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	start := time.Now()
+	tr, err := newTranslator(ctx, cfg)
+	if err == nil {
+		err = tr.loadModels(ctx, cfg)
+	}
+	observerOrNop(cfg.Observer).ObserveNew(ctx, TranslatorEvent{
+		Start:        start,
+		Duration:     time.Since(start),
+		TranslatorID: tr.translatorID(),
+		MemoryBytes:  tr.memoryBytes(),
+		Err:          err,
+	})
+	if err != nil {
+		if tr != nil {
+			// the translator is not returned, so nothing else can close the runtime
+			_ = tr.wasmRuntime.Close(ctx)
+		}
+		return nil, err
+	}
+	return tr, nil
+}
+
+// loadModels loads the main and the pivot models from the config.
+func (t *Translator) loadModels(ctx context.Context, cfg Config) error {
+	var err error
+	t.model, err = t.loadModel(ctx, cfg.FilesBundle)
+	if err != nil {
+		return err
+	}
+	if cfg.Pivot != nil {
+		t.pivotModel, err = t.loadModel(ctx, *cfg.Pivot)
+		if err != nil {
+			return fmt.Errorf("pivot: %w", err)
+		}
+	}
+	return nil
+}
+
+// newTranslator compiles Bergamot module and creates BlockingService instance without loading any models.
+func newTranslator(ctx context.Context, cfg Config) (*Translator, error) {
+	var err error
 	if cfg.BergamotOptions == nil {
 		cfg.BergamotOptions = DefaultBergamotOptions()
 	}
+	cfg.Observer = observerOrNop(cfg.Observer)
 
 	tr := &Translator{
+		id:           translatorIDs.Add(1),
 		embindEngine: embind.CreateEngine(embind.NewConfig()),
 		cfg:          cfg,
 	}
@@ -126,6 +244,9 @@ func New(ctx context.Context, cfg Config) (*Translator, error) {
 		// sentencepiece uses multithreading - so we need WASM threads feature to use Bergamot
 		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesThreads).
 		WithCloseOnContextDone(cfg.WASMUseContext)
+	if cfg.MaxMemoryBytes != 0 {
+		wasmRuntimeConfig = wasmRuntimeConfig.WithMemoryLimitPages(uint32(cfg.MaxMemoryBytes / wasmMemoryPageSize))
+	}
 	if cfg.WASMCache != nil {
 		wasmRuntimeConfig = wasmRuntimeConfig.WithCompilationCache(cfg.WASMCache)
 	}
@@ -135,49 +256,61 @@ func New(ctx context.Context, cfg Config) (*Translator, error) {
 
 	tr.module, err = wasm.CompileBergamot(ctx, tr.wasmRuntime, tr.embindEngine, cfg.CompileConfig)
 	if err != nil {
+		_ = tr.wasmRuntime.Close(ctx)
 		return nil, fmt.Errorf("CompileBergamot: %w", err)
 	}
+
+	tr.svc, err = gen.NewClassBlockingService(tr.embindEngine, ctx, map[string]any{"cacheSize": uint32(cfg.CacheSize)})
+	if err != nil {
+		_ = tr.wasmRuntime.Close(ctx)
+		return nil, fmt.Errorf("failed to get blocking service: %w", err)
+	}
+
+	return tr, nil
+}
+
+// loadModel loads files into WASM module memory and creates a TranslationModel using them.
+func (t *Translator) loadModel(ctx context.Context, files FilesBundle) (*gen.ClassTranslationModel, error) {
 	bundle, err := enrichAlignedMemoriesBundle(
 		ctx,
-		tr.embindEngine,
-		tr.module,
-		newAlignedMemoryDataBundle(cfg.Model, cfg.LexicalShortlist, cfg.Vocabulary),
+		t.embindEngine,
+		t.module,
+		newAlignedMemoryDataBundle(files),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get aligned memory views: %w", err)
 	}
 
-	tr.svc, err = gen.NewClassBlockingService(tr.embindEngine, ctx, map[string]any{"cacheSize": uint32(cfg.CacheSize)})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get blocking service: %w", err)
-	}
-
-	vocabularies, err := gen.NewClassAlignedMemoryList(tr.embindEngine, ctx)
+	vocabularies, err := gen.NewClassAlignedMemoryList(t.embindEngine, ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create aligned memory list: %w", err)
 	}
-	if err := vocabularies.Push_back(ctx, bundle[vocabularyIndex].asEmbindClass()); err != nil {
-		return nil, fmt.Errorf("failed to push back vocabulary: %w", err)
+	// either shared vocabulary or source and target vocabularies are present
+	for _, i := range [...]int{vocabularyIndex, sourceVocabularyIndex, targetVocabularyIndex} {
+		if bundle[i].isEmpty() {
+			continue
+		}
+		if err := vocabularies.Push_back(ctx, bundle[i].asEmbindClass()); err != nil {
+			return nil, fmt.Errorf("failed to push back vocabulary: %w", err)
+		}
 	}
-	bergamotCfg, err := yaml.Marshal(cfg.BergamotOptions)
+	bergamotCfg, err := yaml.Marshal(t.cfg.BergamotOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert bergamot (marian) options to YAML: %w", err)
 	}
-	tr.model, err = gen.NewClassTranslationModel(
-		tr.embindEngine,
+	model, err := gen.NewClassTranslationModel(
+		t.embindEngine,
 		ctx,
 		string(bergamotCfg),
 		bundle[modelIndex].asEmbindClass(),
 		bundle[shortlistIndex].asEmbindClass(),
 		vocabularies,
-		// quality estimation is not used
-		nil,
+		bundle[qualityModelIndex].asEmbindClass(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create translation model: %w", err)
 	}
-
-	return tr, nil
+	return model, nil
 }
 
 // TranslationOptions are equivalent to ResponseOptions in Bergamot.
@@ -188,12 +321,27 @@ func New(ctx context.Context, cfg Config) (*Translator, error) {
 type TranslationOptions struct {
 	// HTML defines if the Translator should remove HTML tags from text and insert them in output.
 	HTML bool
+
+	// Placeholders defines if the Translator should keep placeholders of the text as is: printf verbs (%s, %[1]d),
+	// Go template actions and mustache tokens ({{.Name}}, {{name}}), ICU MessageFormat arguments ({name})
+	// and # in plural branches. Branches of ICU plural and select arguments are translated separately.
+	// If a placeholder is lost in translation, PlaceholderLostError is returned.
+	// Detailed results of requests with protected placeholders contain only Source and Text.
+	Placeholders bool
+
+	// Glossary enforces translations of its terms. It overrides Config.Glossary. Detailed results of requests
+	// with found terms contain only Source, Text and applied glossary entries.
+	Glossary *Glossary
 }
 
 type TranslationRequest struct {
 	// Text to be translated
 	Text string
 
+	// From and To are codes of source and target languages (e.g. "es" and "en").
+	// They are used only by Registry to choose models for translation.
+	From, To string
+
 	// Options for translation
 	Options TranslationOptions
 }
@@ -211,7 +359,66 @@ func (t *Translator) Translate(ctx context.Context, request TranslationRequest)
 }
 
 // TranslateMultiple translates a batch of text provided in the requests into a model target language.
-func (t *Translator) TranslateMultiple(ctx context.Context, requests ...TranslationRequest) ([]string, error) {
+func (t *Translator) TranslateMultiple(ctx context.Context, requests ...TranslationRequest) (output []string, err error) {
+	defer func(start time.Time) {
+		t.observeTranslation(ctx, start, requests, false, err)
+	}(time.Now())
+
+	return translateProtected(requests, t.cfg.Glossary, func(requests []TranslationRequest) ([]string, error) {
+		resp, err := t.translate(ctx, requests)
+		if err != nil {
+			return nil, err
+		}
+		return processResponse(ctx, resp, readTranslatedText)
+	}, translatedText, protectedText)
+}
+
+// TranslateDetailed is similar to Translate, but also returns information about sentences
+// of the original and translated texts.
+func (t *Translator) TranslateDetailed(ctx context.Context, request TranslationRequest) (TranslationResult, error) {
+	results, err := t.TranslateMultipleDetailed(ctx, request)
+	if err != nil {
+		return TranslationResult{}, err
+	}
+	if len(results) < 1 {
+		return TranslationResult{}, fmt.Errorf("expected translation results to have at least 1 element")
+	}
+	return results[0], nil
+}
+
+// TranslateMultipleDetailed is similar to TranslateMultiple, but also returns information about sentences
+// of the original and translated texts.
+func (t *Translator) TranslateMultipleDetailed(
+	ctx context.Context,
+	requests ...TranslationRequest,
+) (results []TranslationResult, err error) {
+	defer func(start time.Time) {
+		t.observeTranslation(ctx, start, requests, true, err)
+	}(time.Now())
+
+	return translateProtected(requests, t.cfg.Glossary, func(requests []TranslationRequest) ([]TranslationResult, error) {
+		resp, err := t.translate(ctx, requests)
+		if err != nil {
+			return nil, err
+		}
+		return processResponse(ctx, resp, readTranslationResult)
+	}, resultText, protectedResult)
+}
+
+func (t *Translator) translate(ctx context.Context, requests []TranslationRequest) (embind.ClassBase, error) {
+	return t.translateWith(ctx, t.model, t.pivotModel, requests)
+}
+
+// translateWith translates requests with given model. If pivotModel is not nil,
+// requests are translated via pivoting.
+func (t *Translator) translateWith(
+	ctx context.Context,
+	model, pivotModel *gen.ClassTranslationModel,
+	requests []TranslationRequest,
+) (embind.ClassBase, error) {
+	if err := checkInputSize(requests, t.cfg.MaxInputBytes); err != nil {
+		return nil, err
+	}
 	input, err := gen.NewClassVectorString(t.embindEngine, ctx)
 	if err != nil {
 		return nil, err
@@ -225,17 +432,33 @@ func (t *Translator) TranslateMultiple(ctx context.Context, requests ...Translat
 	if err := convertToInput(ctx, input, options, requests); err != nil {
 		return nil, err
 	}
-	resp, err := t.svc.Translate(ctx, t.model, input, options)
-	if err != nil {
-		return nil, err
+	if pivotModel != nil {
+		return t.svc.TranslateViaPivoting(ctx, model, pivotModel, input, options)
 	}
-	return processResponse(ctx, resp)
+	return t.svc.Translate(ctx, model, input, options)
 }
 
 // Close deletes created objects and stops the WASM runtime
-func (t *Translator) Close(ctx context.Context) error {
-	if err := t.model.Delete(ctx); err != nil {
-		return err
+func (t *Translator) Close(ctx context.Context) (err error) {
+	defer func(start time.Time) {
+		t.cfg.Observer.ObserveClose(ctx, TranslatorEvent{
+			Start:        start,
+			Duration:     time.Since(start),
+			TranslatorID: t.id,
+			MemoryBytes:  t.memoryBytes(),
+			Err:          err,
+		})
+	}(time.Now())
+
+	if t.model != nil {
+		if err := t.model.Delete(ctx); err != nil {
+			return err
+		}
+	}
+	if t.pivotModel != nil {
+		if err := t.pivotModel.Delete(ctx); err != nil {
+			return err
+		}
 	}
 	if err := t.svc.Delete(ctx); err != nil {
 		return err
@@ -243,6 +466,20 @@ func (t *Translator) Close(ctx context.Context) error {
 	return t.wasmRuntime.Close(ctx)
 }
 
+// discard closes the WASM runtime without deleting objects inside of the module.
+// Used if the module state is broken and calling its functions is unsafe.
+func (t *Translator) discard(ctx context.Context) (err error) {
+	defer func(start time.Time) {
+		t.cfg.Observer.ObserveClose(ctx, TranslatorEvent{
+			Start:        start,
+			Duration:     time.Since(start),
+			TranslatorID: t.id,
+			Err:          err,
+		})
+	}(time.Now())
+	return t.wasmRuntime.Close(ctx)
+}
+
 func convertToInput(
 	ctx context.Context,
 	input *gen.ClassVectorString,
@@ -268,7 +505,10 @@ func convertToInput(
 	return nil
 }
 
-func processResponse(ctx context.Context, resp embind.ClassBase) ([]string, error) {
+// responseReader reads a result from Response for i-th translation request.
+type responseReader[T any] func(ctx context.Context, i uint32, response *gen.ClassResponse) (T, error)
+
+func processResponse[T any](ctx context.Context, resp embind.ClassBase, read responseReader[T]) ([]T, error) {
 	responseVector, ok := resp.(*gen.ClassVectorResponse)
 	if !ok {
 		return nil, fmt.Errorf("expected response to be a Response vector but got %T", resp)
@@ -278,7 +518,7 @@ func processResponse(ctx context.Context, resp embind.ClassBase) ([]string, erro
 	if err != nil {
 		return nil, err
 	}
-	output := make([]string, 0, n)
+	output := make([]T, 0, n)
 	for i := uint32(0); i < n; i++ {
 		rawResponse, err := responseVector.Get(ctx, i)
 		if err != nil {
@@ -288,15 +528,19 @@ func processResponse(ctx context.Context, resp embind.ClassBase) ([]string, erro
 		if !ok {
 			return nil, fmt.Errorf("expected response vector element to be a Response but got %T", rawResponse)
 		}
-		translatedText, err := response.GetTranslatedText(ctx)
+		result, err := read(ctx, i, response)
 		if err != nil {
 			return nil, err
 		}
-		output = append(output, translatedText)
+		output = append(output, result)
 	}
 	return output, nil
 }
 
+func readTranslatedText(ctx context.Context, _ uint32, response *gen.ClassResponse) (string, error) {
+	return response.GetTranslatedText(ctx)
+}
+
 type alignedMemoryInfo struct {
 	file   *alignedMemoryFile
 	memory *gen.ClassAlignedMemory
@@ -315,35 +559,47 @@ func (i alignedMemoryInfo) asEmbindClass() embind.ClassBase {
 	return i.memory
 }
 
-type alignedMemoriesBundle [3]alignedMemoryInfo
+type alignedMemoriesBundle [6]alignedMemoryInfo
 
 const (
 	modelIndex = iota
 	shortlistIndex
 	vocabularyIndex
+	sourceVocabularyIndex
+	targetVocabularyIndex
+	qualityModelIndex
 )
 
 const (
-	modelAlignment      = 256
-	shortlistAlignment  = 64
-	vocabularyAlignment = 64
+	modelAlignment        = 256
+	shortlistAlignment    = 64
+	vocabularyAlignment   = 64
+	qualityModelAlignment = 64
 )
 
-func newAlignedMemoryDataBundle(model, shortlist, vocab io.Reader) alignedMemoriesBundle {
-	return alignedMemoriesBundle{
-		modelIndex: {file: &alignedMemoryFile{
-			Reader:    model,
-			Alignment: modelAlignment,
-		}},
-		shortlistIndex: {file: &alignedMemoryFile{
-			Reader:    shortlist,
-			Alignment: shortlistAlignment,
-		}},
-		vocabularyIndex: {file: &alignedMemoryFile{
-			Reader:    vocab,
-			Alignment: vocabularyAlignment,
-		}},
+func newAlignedMemoryDataBundle(files FilesBundle) alignedMemoriesBundle {
+	var bundle alignedMemoriesBundle
+	for i, file := range [...]struct {
+		reader    io.Reader
+		alignment uint
+	}{
+		modelIndex:            {reader: files.Model, alignment: modelAlignment},
+		shortlistIndex:        {reader: files.LexicalShortlist, alignment: shortlistAlignment},
+		vocabularyIndex:       {reader: files.Vocabulary, alignment: vocabularyAlignment},
+		sourceVocabularyIndex: {reader: files.SourceVocabulary, alignment: vocabularyAlignment},
+		targetVocabularyIndex: {reader: files.TargetVocabulary, alignment: vocabularyAlignment},
+		qualityModelIndex:     {reader: files.QualityModel, alignment: qualityModelAlignment},
+	} {
+		// optional files are left empty
+		if file.reader == nil {
+			continue
+		}
+		bundle[i].file = &alignedMemoryFile{
+			Reader:    file.reader,
+			Alignment: file.alignment,
+		}
 	}
+	return bundle
 }
 
 func enrichAlignedMemoriesBundle(
@@ -358,7 +614,9 @@ func enrichAlignedMemoriesBundle(
 		return alignedMemoriesBundle{}, err
 	}
 	// growing module memory to avoid extra allocations
-	growModuleMemory(mod, bundle)
+	if err := growModuleMemory(mod, bundle); err != nil {
+		return alignedMemoriesBundle{}, err
+	}
 
 	for i := range bundle {
 		if bundle[i].isEmpty() {
@@ -404,21 +662,27 @@ func enrichBundleWithSizes(ctx context.Context, bundle alignedMemoriesBundle) (a
 	return bundle, err
 }
 
-const wasmMemoryPageSize = uint32(65536)
+const (
+	wasmMemoryPageSize = 65536
+	// WASM32 memory can't exceed 65536 pages
+	wasmMaxMemoryBytes = uint64(65536 * wasmMemoryPageSize)
+)
 
-func growModuleMemory(mod api.Module, bundle alignedMemoriesBundle) {
-	var size uint32
+// growModuleMemory grows module memory to fit files of the bundle.
+// Returns ErrOutOfMemory if the memory can't grow, e.g. because of MaxMemoryBytes limit.
+func growModuleMemory(mod api.Module, bundle alignedMemoriesBundle) error {
+	var size uint64
 	for i := range bundle {
 		if bundle[i].isEmpty() {
 			continue
 		}
-		size += bundle[i].size
+		size += uint64(bundle[i].size)
 	}
 
 	mem := mod.Memory()
-	availableSize := mem.Size()
+	availableSize := uint64(mem.Size())
 	if availableSize >= size {
-		return
+		return nil
 	}
 
 	requiredSize := size - availableSize
@@ -427,7 +691,10 @@ func growModuleMemory(mod api.Module, bundle alignedMemoriesBundle) {
 	if pages*wasmMemoryPageSize < requiredSize {
 		pages += 1
 	}
-	mem.Grow(pages)
+	if _, ok := mem.Grow(uint32(pages)); !ok {
+		return fmt.Errorf("%w: failed to grow memory of %d bytes to fit %d bytes of files", ErrOutOfMemory, availableSize, size)
+	}
+	return nil
 }
 
 func getAlignedMemoryByteView(ctx context.Context, memory *gen.ClassAlignedMemory) ([]int8, error) {
@@ -450,15 +717,20 @@ func fillBundleViews(ctx context.Context, bundle alignedMemoriesBundle) (aligned
 		}
 		i := i
 		eg.Go(func() error {
-			return fillByteArrayView(ctx, bundle[i].view, bundle[i].file.Reader, bundle[i].size)
+			return fillByteArrayView(ctx, bundle[i].view, bundle[i].file, bundle[i].size)
 		})
 	}
 	err := eg.Wait()
 	return bundle, err
 }
 
-func fillByteArrayView(ctx context.Context, view []int8, input io.Reader, size uint32) error {
+func fillByteArrayView(ctx context.Context, view []int8, file *alignedMemoryFile, size uint32) error {
 	viewBytes := *(*[]byte)(unsafe.Pointer(&view))
+	if file.compression != compressionNone {
+		return decompressIntoByteArrayView(viewBytes, file, size)
+	}
+
+	input := file.Reader
 	if bytesProvider, ok := input.(readerWithBytes); ok {
 		copy(viewBytes, bytesProvider.Bytes())
 		return nil
@@ -476,3 +748,22 @@ func fillByteArrayView(ctx context.Context, view []int8, input io.Reader, size u
 	}
 	return nil
 }
+
+// decompressIntoByteArrayView decompresses file data directly into the byte view.
+func decompressIntoByteArrayView(viewBytes []byte, file *alignedMemoryFile, size uint32) error {
+	input, err := file.decompressedReader()
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	written, err := io.ReadFull(input, viewBytes[:size])
+	if err != nil {
+		return fmt.Errorf("only wrote %d/%d bytes: %w", written, size, err)
+	}
+	// checking that decompressed data size matches the size from gzip trailer or zstd frame headers
+	if n, _ := input.Read(make([]byte, 1)); n != 0 {
+		return fmt.Errorf("decompressed data exceeds expected size %d", size)
+	}
+	return nil
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"errors"
 	"io"
 	"testing"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 
 	"github.com/KSpaceer/gobergamot"
 	"github.com/KSpaceer/gobergamot/internal/wasm"
@@ -20,6 +23,10 @@ func TestTranslator_New(t *testing.T) {
 		name    string
 		cfg     gobergamot.Config
 		wantErr bool
+		// expected error if it is known
+		wantErrIs error
+		// prepare completes the config in the subtest
+		prepare func(t *testing.T, cfg *gobergamot.Config)
 	}{
 		{
 			name: "no model",
@@ -64,6 +71,79 @@ func TestTranslator_New(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "no target vocabulary",
+			cfg: gobergamot.Config{
+				FilesBundle: gobergamot.FilesBundle{
+					Model:            bytes.NewReader(nil),
+					LexicalShortlist: bytes.NewReader(nil),
+					SourceVocabulary: bytes.NewReader(nil),
+				},
+				WASMCache: cache,
+			},
+			wantErr: true,
+		},
+		{
+			name: "ambiguous vocabulary",
+			cfg: gobergamot.Config{
+				FilesBundle: gobergamot.FilesBundle{
+					Model:            bytes.NewReader(nil),
+					LexicalShortlist: bytes.NewReader(nil),
+					Vocabulary:       bytes.NewReader(nil),
+					SourceVocabulary: bytes.NewReader(nil),
+					TargetVocabulary: bytes.NewReader(nil),
+				},
+				WASMCache: cache,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid with separate vocabularies",
+			cfg: gobergamot.Config{
+				FilesBundle: gobergamot.FilesBundle{
+					Model:            bytes.NewBuffer(testModel),
+					LexicalShortlist: bytes.NewBuffer(testShortlist),
+					SourceVocabulary: bytes.NewBuffer(testVocabulary),
+					TargetVocabulary: bytes.NewBuffer(testVocabulary),
+				},
+				WASMCache: cache,
+			},
+			wantErr: false,
+		},
+		{
+			name: "no pivot vocabulary",
+			cfg: gobergamot.Config{
+				FilesBundle: testBundle(t),
+				Pivot: &gobergamot.FilesBundle{
+					Model:            bytes.NewReader(nil),
+					LexicalShortlist: bytes.NewReader(nil),
+				},
+				WASMCache: cache,
+			},
+			wantErr: true,
+		},
+		{
+			name: "memory limit below page size",
+			cfg: gobergamot.Config{
+				FilesBundle:    testBundle(t),
+				WASMCache:      cache,
+				MaxMemoryBytes: 1024,
+			},
+			wantErr: true,
+		},
+		{
+			name: "memory limit below model size",
+			cfg: gobergamot.Config{
+				FilesBundle: testBundle(t),
+				WASMCache:   cache,
+			},
+			prepare: func(t *testing.T, cfg *gobergamot.Config) {
+				// the module is instantiated, but the memory can't grow to fit the model
+				cfg.MaxMemoryBytes = initialMemoryBytes(t) + uint64(len(testModel))/2
+			},
+			wantErr:   true,
+			wantErrIs: gobergamot.ErrOutOfMemory,
+		},
 		{
 			name: "valid",
 			cfg: gobergamot.Config{
@@ -72,6 +152,14 @@ func TestTranslator_New(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid with compressed files",
+			cfg: gobergamot.Config{
+				FilesBundle: gzipBundle(t, testBundle(t)),
+				WASMCache:   cache,
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid with slow paths",
 			cfg: gobergamot.Config{
@@ -84,6 +172,9 @@ func TestTranslator_New(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.prepare != nil {
+				tt.prepare(t, &tt.cfg)
+			}
 			stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 			tt.cfg.CompileConfig.Stdout = stdout
 			tt.cfg.CompileConfig.Stderr = stderr
@@ -97,6 +188,9 @@ func TestTranslator_New(t *testing.T) {
 					stderr.String(),
 				)
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Fatalf("New() error = %v, want %v", err, tt.wantErrIs)
+			}
 			if tt.wantErr {
 				return
 			}
@@ -193,6 +287,49 @@ func TestTranslator_Translate(t *testing.T) {
 	}
 }
 
+func TestTranslator_TranslateDetailed(t *testing.T) {
+	ctx := context.Background()
+
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	translator, err := gobergamot.New(ctx, gobergamot.Config{
+		CompileConfig: wasm.CompileConfig{
+			Stderr: stderr,
+			Stdout: stdout,
+		},
+		FilesBundle: testBundle(t),
+	})
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	defer func() {
+		if err := translator.Close(ctx); err != nil {
+			t.Fatalf("failed to close translator: %v", err)
+		}
+	}()
+
+	result, err := translator.TranslateDetailed(ctx, gobergamot.TranslationRequest{
+		Text: "Hello, World! Goodbye, World!",
+	})
+	if err != nil {
+		t.Fatalf("got error %v\n\nstdout: %s\n\nstderr: %s", err, stdout.String(), stderr.String())
+	}
+	if result.Source != "Hello, World! Goodbye, World!" {
+		t.Errorf("unexpected source %q", result.Source)
+	}
+	if len(result.Sentences) != 2 {
+		t.Fatalf("expected 2 sentences, got %d", len(result.Sentences))
+	}
+	for i, sentence := range result.Sentences {
+		if sentence.Target.End > len(result.Text) || sentence.Source.End > len(result.Source) {
+			t.Errorf("sentence %d ranges are out of text bounds", i)
+		}
+		if sentence.Source.Begin >= sentence.Source.End || sentence.Target.Begin >= sentence.Target.End {
+			t.Errorf("sentence %d has empty ranges: %+v", i, sentence)
+		}
+	}
+}
+
 //go:embed testdata/model.enru.intgemm.alphas.bin
 var testModel []byte
 
@@ -222,6 +359,45 @@ func strictReaderWrapper(bundle gobergamot.FilesBundle) gobergamot.FilesBundle {
 	}
 }
 
+// initialMemoryBytes returns size of the memory the module is instantiated with.
+func initialMemoryBytes(t *testing.T) uint64 {
+	t.Helper()
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads))
+	defer runtime.Close(ctx)
+
+	compiled, err := runtime.CompileModule(ctx, wasm.BergamotWASM())
+	if err != nil {
+		t.Fatalf("failed to compile module: %v", err)
+	}
+	var pages uint32
+	for _, memory := range compiled.ExportedMemories() {
+		pages = max(pages, memory.Min())
+	}
+	for _, memory := range compiled.ImportedMemories() {
+		pages = max(pages, memory.Min())
+	}
+	return uint64(pages) * 65536
+}
+
+// compressing files with gzip to check transparent decompression.
+func gzipBundle(t *testing.T, bundle gobergamot.FilesBundle) gobergamot.FilesBundle {
+	t.Helper()
+	compress := func(r io.Reader) io.Reader {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		return bytes.NewReader(gzipped(t, string(data)))
+	}
+	return gobergamot.FilesBundle{
+		Model:            compress(bundle.Model),
+		LexicalShortlist: compress(bundle.LexicalShortlist),
+		Vocabulary:       compress(bundle.Vocabulary),
+	}
+}
+
 type readerWrapper struct {
 	r io.Reader
 }